@@ -20,3 +20,27 @@ func (t *TextureAtlas) Coords(u, v int) (umin, umax, vmin, vmax float32) {
 	vmax = (16.0 * float32(v+1)) / float32(size.Y)
 	return
 }
+
+const (
+	// glyphs of the bitmap font are stored in ascii order from fontFirstChar,
+	// in rows of fontColumns tiles starting at column fontColumn of the atlas
+	fontFirstChar = ' '
+	fontLastChar  = '~'
+	fontColumn    = 54
+	fontColumns   = 10
+
+	// glyphs are left aligned in their tile, width of the glyphs and horizontal advance relative to the tile size
+	fontGlyphWidth = 0.625
+	fontAdvance    = 0.75
+)
+
+// Returns the coords of the glyph of a character in the bitmap font.
+// Characters missing from the font show as '?'.
+func (t *TextureAtlas) Glyph(c rune) [2]int {
+	if c < fontFirstChar || c > fontLastChar {
+		c = '?'
+	}
+
+	idx := int(c - fontFirstChar)
+	return [2]int{fontColumn + idx%fontColumns, idx / fontColumns}
+}
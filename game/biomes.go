@@ -0,0 +1,152 @@
+package game
+
+// Identifies a biome in the biomes table.
+type BiomeID uint8
+
+const (
+	biomePlains BiomeID = iota
+	biomeDesert
+	biomeForest
+	biomeTaiga
+	biomeSnowyMountains
+	biomeSwamp
+	biomeOcean
+)
+
+// Biome defines the terrain shape, blocks and vegetation of a region of the world.
+type Biome struct {
+	name string
+
+	// terrain height is baseHeight plus up to heightVariation from the terrain noise
+	baseHeight      float32
+	heightVariation float32
+
+	// top block of the terrain and the blocks under it down to surfaceDepth
+	surface string
+	filler  string
+
+	// trunk types picked randomly for trees, no trees if empty
+	trunks []string
+
+	// leaves of the trees, none if empty (e.g. cactuses)
+	leaves string
+
+	// chance of each tree attempt in a chunk to grow a tree
+	treeDensity float32
+}
+
+// Climate at a column of the world, every parameter is in [0,1].
+type Climate struct {
+	temperature     float32
+	humidity        float32
+	continentalness float32
+}
+
+// All the biomes indexed by id.
+var biomes = [...]Biome{
+	biomePlains: {
+		name:            "plains",
+		baseHeight:      100,
+		heightVariation: 10,
+		surface:         "dirt-grass",
+		filler:          "dirt",
+		trunks:          []string{"wood"},
+		leaves:          "leaves-flower",
+		treeDensity:     0.15,
+	},
+	biomeDesert: {
+		name:            "desert",
+		baseHeight:      98,
+		heightVariation: 12,
+		surface:         "sand",
+		filler:          "sand",
+		trunks:          []string{"cactus"},
+		treeDensity:     0.2,
+	},
+	biomeForest: {
+		name:            "forest",
+		baseHeight:      103,
+		heightVariation: 16,
+		surface:         "dirt-grass",
+		filler:          "dirt",
+		trunks:          []string{"wood", "white-wood"},
+		leaves:          "leaves",
+		treeDensity:     1.0,
+	},
+	biomeTaiga: {
+		name:            "taiga",
+		baseHeight:      106,
+		heightVariation: 20,
+		surface:         "dirt-snow",
+		filler:          "dirt",
+		trunks:          []string{"dark-wood"},
+		leaves:          "leaves",
+		treeDensity:     0.6,
+	},
+	biomeSnowyMountains: {
+		name:            "snowy mountains",
+		baseHeight:      125,
+		heightVariation: 70,
+		surface:         "dirt-snow",
+		filler:          "stone",
+	},
+	biomeSwamp: {
+		name:            "swamp",
+		baseHeight:      96,
+		heightVariation: 3,
+		surface:         "dirt-wet-grass",
+		filler:          "dirt",
+		trunks:          []string{"wood"},
+		leaves:          "leaves",
+		treeDensity:     0.3,
+	},
+	biomeOcean: {
+		name:            "ocean",
+		baseHeight:      78,
+		heightVariation: 14,
+		surface:         "sand",
+		filler:          "sand",
+	},
+}
+
+const (
+	// climate thresholds picking the biome
+	oceanContinentalness    = 0.25
+	mountainContinentalness = 0.75
+	desertTemperature       = 0.6
+	desertHumidity          = 0.45
+	swampHumidity           = 0.65
+	swampTemperature        = 0.45
+	taigaTemperature        = 0.35
+	forestHumidity          = 0.5
+)
+
+// Returns the biome matching the climate.
+func selectBiome(c Climate) BiomeID {
+	switch {
+	case c.continentalness < oceanContinentalness:
+		return biomeOcean
+	case c.continentalness > mountainContinentalness:
+		return biomeSnowyMountains
+	case c.temperature > desertTemperature && c.humidity < desertHumidity:
+		return biomeDesert
+	case c.humidity > swampHumidity && c.temperature > swampTemperature:
+		return biomeSwamp
+	case c.temperature < taigaTemperature:
+		return biomeTaiga
+	case c.humidity > forestHumidity:
+		return biomeForest
+	default:
+		return biomePlains
+	}
+}
+
+// Returns the biome definition of the id.
+func (id BiomeID) Biome() *Biome {
+	return &biomes[id]
+}
+
+// Returns the name of the biome.
+func (id BiomeID) String() string {
+	return biomes[id].name
+}
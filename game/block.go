@@ -17,6 +17,12 @@ type Block struct {
 
 	// if the block is physically active
 	active bool
+
+	// light reaching the block from the sky and from emitting blocks, up to maxLightLevel
+	skyLight, blockLight uint8
+
+	// values of the state properties of the block type (e.g. fluid level, facing)
+	state BlockState
 }
 
 // TargetBlock holds captures the block being looked at.
@@ -25,6 +31,9 @@ type TargetBlock struct {
 
 	// the side that is being looked at
 	face Direction
+
+	// world position where the line of sight hits the block
+	hitPos mgl32.Vec3
 }
 
 // Wrapper over Vertex that holds the face and normal vector associated with the block face.
@@ -32,6 +41,9 @@ type BlockVertex struct {
 	Vertex
 	norm mgl32.Vec3
 	face Direction
+
+	// if the face is inside the block, lit by the block itself instead of the block in front of it
+	inner bool
 }
 
 const blockSize = 1.0
@@ -61,7 +73,7 @@ func (b *Block) Translate() mgl32.Mat4 {
 	)
 }
 
-// Returns bounding box around block.
+// Returns bounding box around block, the cell it fills whatever its shape.
 func (b *Block) Box() Box {
 	half := float32(blockSize / 2.0)
 	min := b.WorldPos().Sub(mgl32.Vec3{
@@ -77,9 +89,14 @@ func (b *Block) Box() Box {
 	return newBox(min, max)
 }
 
-// Returns vertices for a block with texture and normal vector.
+// Returns vertices for a block with texture and normal vector, shaped and oriented like the block.
 func (b *Block) Vertices(excludeFaces [6]bool) []BlockVertex {
-	texs := blocks[b.blockType]
+	return blockVertices(b.chunk.atlas, b.blockType, b.state, excludeFaces)
+}
+
+// Returns vertices for a unit cube centered at the origin textured as the block type placed along the axis.
+func cubeVertices(atlas *TextureAtlas, blockType string, axis Axis, excludeFaces [6]bool) []BlockVertex {
+	texs := blocks[blockType]
 	out := make([]BlockVertex, 0)
 	for i := range directions {
 		if excludeFaces[i] {
@@ -87,9 +104,14 @@ func (b *Block) Vertices(excludeFaces [6]bool) []BlockVertex {
 		}
 
 		dir := Direction(i)
-		tex := texs[dir]
-		umin, umax, vmin, vmax := b.chunk.atlas.Coords(tex[0], tex[1])
-		quad := newQuad(umin, umax, vmin, vmax).TranlateDirection(dir)
+		texDir, rotated := axisFace(axis, dir)
+		tex := texs[texDir]
+		umin, umax, vmin, vmax := atlas.Coords(tex[0], tex[1])
+		quad := newQuad(umin, umax, vmin, vmax)
+		if rotated {
+			quad = quad.RotateTexture(umin, umax, vmin, vmax)
+		}
+		quad = quad.TranlateDirection(dir)
 		norm := dir.Normal()
 
 		for _, fv := range quad {
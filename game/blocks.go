@@ -146,4 +146,219 @@ var blocks = map[string][6][2]int{
 		{16, 27},
 		{16, 27},
 	},
+	"planks": {
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+	},
+	"white-planks": {
+		{14, 2},
+		{14, 2},
+		{14, 2},
+		{14, 2},
+		{14, 2},
+		{14, 2},
+	},
+	"dark-planks": {
+		{0, 12},
+		{0, 12},
+		{0, 12},
+		{0, 12},
+		{0, 12},
+		{0, 12},
+	},
+	"stone-bricks": {
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+	},
+	"copper-ore": {
+		{22, 0},
+		{22, 0},
+		{22, 0},
+		{22, 0},
+		{22, 0},
+		{22, 0},
+	},
+	"iron-ore": {
+		{22, 4},
+		{22, 4},
+		{22, 4},
+		{22, 4},
+		{22, 4},
+		{22, 4},
+	},
+	"gold-ore": {
+		{22, 3},
+		{22, 3},
+		{22, 3},
+		{22, 3},
+		{22, 3},
+		{22, 3},
+	},
+	"redstone-ore": {
+		{22, 6},
+		{22, 6},
+		{22, 6},
+		{22, 6},
+		{22, 6},
+		{22, 6},
+	},
+	"lapis-ore": {
+		{22, 5},
+		{22, 5},
+		{22, 5},
+		{22, 5},
+		{22, 5},
+		{22, 5},
+	},
+	"emerald-ore": {
+		{24, 5},
+		{24, 5},
+		{24, 5},
+		{24, 5},
+		{24, 5},
+		{24, 5},
+	},
+	"glowstone": {
+		{27, 7},
+		{27, 7},
+		{27, 7},
+		{27, 7},
+		{27, 7},
+		{27, 7},
+	},
+	"lamp": {
+		{27, 5},
+		{27, 5},
+		{27, 5},
+		{27, 5},
+		{27, 5},
+		{27, 5},
+	},
+	"stone-slab": {
+		{21, 27},
+		{21, 27},
+		{21, 27},
+		{21, 27},
+		{21, 27},
+		{21, 27},
+	},
+	"cobblestone-slab": {
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+	},
+	"planks-slab": {
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+	},
+	"stone-bricks-slab": {
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+	},
+	"cobblestone-stairs": {
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+		{2, 15},
+	},
+	"planks-stairs": {
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+		{10, 1},
+	},
+	"stone-bricks-stairs": {
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+		{7, 30},
+	},
+	"dandelion": {
+		{19, 14},
+		{19, 14},
+		{19, 14},
+		{19, 14},
+		{19, 14},
+		{19, 14},
+	},
+	"cornflower": {
+		{16, 13},
+		{16, 13},
+		{16, 13},
+		{16, 13},
+		{16, 13},
+		{16, 13},
+	},
+	"white-flower": {
+		{8, 0},
+		{8, 0},
+		{8, 0},
+		{8, 0},
+		{8, 0},
+		{8, 0},
+	},
+	"mushroom": {
+		{15, 9},
+		{15, 9},
+		{15, 9},
+		{15, 9},
+		{15, 9},
+		{15, 9},
+	},
+	"ice": {
+		{26, 10},
+		{26, 10},
+		{26, 10},
+		{26, 10},
+		{26, 10},
+		{26, 10},
+	},
+	"water": {
+		{3, 2},
+		{3, 2},
+		{3, 2},
+		{3, 2},
+		{3, 2},
+		{3, 2},
+	},
+	"lava": {
+		{4, 2},
+		{4, 2},
+		{4, 2},
+		{4, 2},
+		{4, 2},
+		{4, 2},
+	},
+}
+
+// max number of blocks per inventory slot for block types that differ from defaultStackSize
+var blockStackSizes = map[string]int{
+	"bedrock":     1,
+	"diamond-ore": 16,
+	"emerald-ore": 16,
 }
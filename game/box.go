@@ -18,6 +18,12 @@ func newBox(min, max mgl32.Vec3) Box {
 	}
 }
 
+// Returns the box of the block sized cell of the world containing the position.
+func cellBox(pos mgl32.Vec3) Box {
+	min := mgl32.Vec3{floor(pos.X()), floor(pos.Y()), floor(pos.Z())}
+	return newBox(min, min.Add(mgl32.Vec3{blockSize, blockSize, blockSize}))
+}
+
 // Measures and returns the distance between the box and a given position.
 func (b Box) Distance(pos mgl32.Vec3) float32 {
 	min := b.min
@@ -1,8 +1,6 @@
 package game
 
 import (
-	"math"
-
 	"github.com/go-gl/mathgl/mgl32"
 )
 
@@ -27,7 +25,6 @@ type Camera struct {
 const (
 	// limits
 	fov        = 45.0
-	cullFov    = 60.0
 	near       = 0.01
 	far        = 1000.0
 	pitchLimit = 0.99
@@ -89,21 +86,7 @@ func (c *Camera) Look(screenX, screenY float32) {
 	c.view = rotation.Mul4x1(dir).Vec3().Normalize()
 }
 
-// Returns a frustrum used for culling.
-// FIXME: top/bottom is off
+// Returns the frustrum of the camera used for culling.
 func (c *Camera) Frustrum() *Frustrum {
-	halfV := float32(far * math.Tan(float64(mgl32.DegToRad(cullFov)*0.5)))
-	halfH := halfV * aspect
-	f := &Frustrum{}
-	farVec := c.view.Mul(far)
-	upVec := c.up.Mul(halfV)
-	right := c.cross()
-	rightVec := c.cross().Mul(halfH)
-	f.near = newPlane(c.view, c.pos.Add(c.view.Mul(near)))
-	f.far = newPlane(c.view.Mul(-1), c.pos.Add(farVec))
-	f.right = newPlane(farVec.Add(rightVec).Cross(c.up.Mul(-1)), c.pos)
-	f.left = newPlane(farVec.Sub(rightVec).Cross(c.up), c.pos)
-	f.top = newPlane(farVec.Add(upVec).Cross(right), c.pos)
-	f.bottom = newPlane(farVec.Sub(upVec).Cross(right), c.pos)
-	return f
+	return newFrustrum(c.Mat())
 }
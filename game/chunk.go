@@ -1,11 +1,17 @@
 package game
 
 import (
+	"cmp"
+	"slices"
+	"time"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-// Chunk groups blocks for rendering and operations.
+// Chunk groups a column of blocks for rendering and operations.
+// The column is split in sections of chunkSectionSize blocks stacked from the bottom of the world,
+// sections without blocks are not stored nor drawn.
 type Chunk struct {
 	// from db, can be empty
 	id int
@@ -14,202 +20,356 @@ type Chunk struct {
 	atlas                   *TextureAtlas
 	shader, shadowMapShader *Shader
 
-	// blocks in the chunk, position determined by index in array
-	blocks [chunkWidth][chunkHeight][chunkWidth]*Block
-
-	// total count of vertices in the chunk
-	vertCount int
+	// sections from the bottom up, nil if the section has no blocks
+	sections []*ChunkSection
 
 	// world postion of the chunk (corner)
 	pos mgl32.Vec3
 
-	// gpu buffers
-	vao, vbo             uint32
-	shadowVao, shadowVbo uint32
+	// returns the block at world coordinates without creating sections, for the faces on the chunk border
+	discoverBlock func(x, y, z int) *Block
+
+	// returns the light levels at world coordinates, for the faces on the chunk border
+	discoverLight func(x, y, z int) (sky, block int)
+
+	// when the chunk was spawned, it fades in for chunkFadeDuration after it
+	spawned time.Time
+}
+
+// ChunkSection holds a cube of blocks of a chunk and their vertices on the GPU.
+type ChunkSection struct {
+	chunk *Chunk
+
+	// height of the bottom of the section in the chunk
+	y int
+
+	// blocks in the section, position determined by index in array
+	blocks [chunkWidth][chunkSectionSize][chunkWidth]*Block
+
+	// total count of vertices in the section
+	vertCount int
+
+	// count of vertices of the translucent blocks, drawn in a separate pass
+	translucentVertCount int
+
+	// blocks changed since the section was last buffered
+	dirty bool
+
+	// pairs of faces connected through non opaque blocks, see Connected
+	connections uint64
+
+	// if the section was reached by the occlusion culling this frame
+	visible bool
+
+	// gpu buffers, created the first time the section has vertices
+	vao, vbo                       uint32
+	shadowVao, shadowVbo           uint32
+	translucentVao, translucentVbo uint32
 }
 
-func newBlockTypes() BlockTypes {
-	return [chunkWidth][chunkHeight][chunkWidth]string{}
+func newBlockTypes(height int) BlockTypes {
+	var types BlockTypes
+	for i := range types {
+		types[i] = make([][chunkWidth]string, height)
+	}
+	return types
 }
 
 // dimensions
 const (
-	chunkWidth  = 16
-	chunkHeight = 256
+	chunkWidth       = 16
+	chunkSectionSize = 16
 )
 
-func newChunk(shader, shadowMapShader *Shader, atlas *TextureAtlas, pos mgl32.Vec3) *Chunk {
+// time taken by a chunk to fade in after it is spawned instead of popping in
+const chunkFadeDuration = time.Millisecond * 600
+
+func newChunk(shader, shadowMapShader *Shader, atlas *TextureAtlas, pos mgl32.Vec3, height int) *Chunk {
 	c := &Chunk{}
 	c.id = -1
 	c.shader = shader
 	c.shadowMapShader = shadowMapShader
 	c.pos = pos
 	c.atlas = atlas
+	c.sections = make([]*ChunkSection, height/chunkSectionSize)
+	c.spawned = time.Now()
 	return c
 }
 
-// Initialize the chunk metadata on the GPU.
-// Sets the given block to be active as the first block.
+// Initializes the blocks of the chunk from the block types.
+// Only the sections with at least one block are created.
 func (c *Chunk) Init(types BlockTypes) {
-	gl.UseProgram(c.shader.handle)
+	for s := range c.sections {
+		if !hasBlocks(types, s*chunkSectionSize) {
+			continue
+		}
+
+		section := c.section(s)
+		for i := range chunkWidth {
+			for j := range chunkSectionSize {
+				for k := range chunkWidth {
+					t := types[i][section.y+j][k]
+					if t != "" {
+						b := section.blocks[i][j][k]
+						b.active = true
+						b.blockType = t
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns true if the section of the block types starting at height y has a block.
+func hasBlocks(types BlockTypes, y int) bool {
 	for i := range chunkWidth {
-		for j := range chunkHeight {
+		for j := y; j < y+chunkSectionSize; j++ {
 			for k := range chunkWidth {
-				b := newBlock(c, i, j, k, "bedrock")
-				c.blocks[i][j][k] = b
-
-				t := types[i][j][k]
-				if t != "" {
-					b.active = true
-					b.blockType = t
-				} else {
-					b.active = false
+				if types[i][j][k] != "" {
+					return true
 				}
-
 			}
 		}
 	}
+	return false
+}
 
-	// gen vao and vbo
-	gl.GenVertexArrays(1, &c.vao)
-	gl.BindVertexArray(c.vao)
-	gl.GenBuffers(1, &c.vbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, c.vbo)
-
-	// configure the attributes
-	vertAttrib := uint32(gl.GetAttribLocation(c.shader.handle, gl.Str("vert\x00")))
-	gl.EnableVertexAttribArray(vertAttrib)
-	gl.VertexAttribPointerWithOffset(vertAttrib, 3, gl.FLOAT, false, 8*4, 0)
-
-	// configure the attributes
-	normAttrib := uint32(gl.GetAttribLocation(c.shader.handle, gl.Str("normal\x00")))
-	gl.EnableVertexAttribArray(normAttrib)
-	gl.VertexAttribPointerWithOffset(normAttrib, 3, gl.FLOAT, false, 8*4, 3*4)
-
-	texAttrib := uint32(gl.GetAttribLocation(c.shader.handle, gl.Str("texCoord\x00")))
-	gl.EnableVertexAttribArray(texAttrib)
-	gl.VertexAttribPointerWithOffset(texAttrib, 2, gl.FLOAT, false, 8*4, 6*4)
+// Returns the height of the chunk in blocks.
+func (c *Chunk) Height() int {
+	return len(c.sections) * chunkSectionSize
+}
 
-	textureUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("tex\x00"))
-	gl.Uniform1i(textureUniform, 0)
+// Returns the block at i,j,k of the chunk, creating its section if it has no blocks yet.
+// Only used to change the block, reads use existingBlock so air is never stored.
+// j should be inside the chunk height.
+func (c *Chunk) Block(i, j, k int) *Block {
+	return c.section(j / chunkSectionSize).blocks[i][j%chunkSectionSize][k]
+}
 
-	shadowMapUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("shadowMap\x00"))
-	gl.Uniform1i(shadowMapUniform, 1)
+// Returns the block at i,j,k of the chunk without creating sections.
+// Returns nil outside the chunk or in a section without blocks.
+func (c *Chunk) existingBlock(i, j, k int) *Block {
+	if i < 0 || i >= chunkWidth || j < 0 || j >= c.Height() || k < 0 || k >= chunkWidth {
+		return nil
+	}
 
-	// shadow map pass
-	gl.GenVertexArrays(1, &c.shadowVao)
-	gl.BindVertexArray(c.shadowVao)
-	gl.GenBuffers(1, &c.shadowVbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, c.shadowVbo)
-	vertAttribShadow := uint32(gl.GetAttribLocation(c.shadowMapShader.handle, gl.Str("vert\x00")))
-	gl.EnableVertexAttribArray(vertAttribShadow)
-	gl.VertexAttribPointerWithOffset(vertAttribShadow, 3, gl.FLOAT, false, 3*4, 0)
+	s := c.sections[j/chunkSectionSize]
+	if s == nil {
+		return nil
+	}
+	return s.blocks[i][j%chunkSectionSize][k]
 }
 
-// Deletes buffers from gpu.
-func (c *Chunk) Destroy() {
-	gl.DeleteBuffers(1, &c.vbo)
-	c.vbo = 0
-	gl.DeleteVertexArrays(1, &c.vao)
-	c.vao = 0
-
-	gl.DeleteBuffers(1, &c.shadowVbo)
-	c.shadowVbo = 0
-	gl.DeleteVertexArrays(1, &c.shadowVao)
-	c.shadowVao = 0
+// Returns the block at i,j,k of the chunk without creating sections.
+// Positions beside the chunk are looked up in the neighbouring chunks.
+func (c *Chunk) blockAt(i, j, k int) *Block {
+	if i < 0 || i >= chunkWidth || k < 0 || k >= chunkWidth {
+		if c.discoverBlock == nil {
+			return nil
+		}
+		return c.discoverBlock(int(c.pos.X())+i, int(c.pos.Y())+j, int(c.pos.Z())+k)
+	}
+	return c.existingBlock(i, j, k)
 }
 
-// Sends the chunks vertices to GPU.
-func (c *Chunk) Buffer() {
-	// reset vertCount
-	c.vertCount = 0
+// Returns the sky and block light levels at i,j,k of the chunk, lighting the faces in front of it.
+// Positions beside the chunk are looked up in the neighbouring chunks.
+// Sections without blocks are lit by the sky only if it reaches them, positions below the world are dark.
+func (c *Chunk) lightAt(i, j, k int) (sky, block int) {
+	if j < 0 {
+		return 0, 0
+	}
+	if j >= c.Height() {
+		return maxLightLevel, 0
+	}
 
-	// start building chunk
-	chunk := make([]float32, 0)
-	chunkDepth := make([]float32, 0)
-	for i, layer := range c.blocks {
-		for j, row := range layer {
-			for k, block := range row {
-				if block == nil || !block.active {
-					continue
-				}
+	if i < 0 || i >= chunkWidth || k < 0 || k >= chunkWidth {
+		if c.discoverLight == nil {
+			return maxLightLevel, 0
+		}
+		return c.discoverLight(int(c.pos.X())+i, int(c.pos.Y())+j, int(c.pos.Z())+k)
+	}
 
-				// get vertices for visible faces only
-				var excludeFaces [6]bool
-				checkExclude := func(i, j, k int, face Direction) {
-					if i < 0 || i >= chunkWidth || j < 0 || j >= chunkHeight || k < 0 || k >= chunkWidth {
-						return
-					}
+	b := c.existingBlock(i, j, k)
+	if b == nil {
+		if c.skyOpen(i, j, k) {
+			return maxLightLevel, 0
+		}
+		return 0, 0
+	}
+	return int(b.skyLight), int(b.blockLight)
+}
 
-					b := c.blocks[i][j][k]
-					if b.active {
-						excludeFaces[face] = true
-					}
-				}
-				checkExclude(i, j, k+1, south)
-				checkExclude(i, j, k-1, north)
-				checkExclude(i, j+1, k, up)
-				checkExclude(i, j-1, k, down)
-				checkExclude(i-1, j, k, west)
-				checkExclude(i+1, j, k, east)
+// Returns true if full sky light reaches i,j,k of the chunk in a section without blocks,
+// which is when the first section above with blocks lets it down the column.
+func (c *Chunk) skyOpen(i, j, k int) bool {
+	for s := j/chunkSectionSize + 1; s < len(c.sections); s++ {
+		if section := c.sections[s]; section != nil {
+			return section.blocks[i][0][k].skyLight == maxLightLevel
+		}
+	}
+	return true
+}
 
-				// translate vertices to respective pos in chunk
-				translate := block.Translate()
-				for _, vert := range block.Vertices(excludeFaces) {
-					c.vertCount++
-					pos := translate.Mul4x1(vert.pos.Vec4(1))
+// Returns the ambient occlusion of the corner of a face, from 0 (occluded) to 3 (open).
+// The corner is lit less for each opaque block touching it in front of the face,
+// and not at all between two opaque blocks.
+func (c *Chunk) cornerOcclusion(i, j, k int, face Direction, corner mgl32.Vec3) int {
+	n := lightOffsets[face]
+
+	// offsets towards the corner along the two axes of the face
+	var sides [2][3]int
+	side := 0
+	for axis := range 3 {
+		if n[axis] != 0 {
+			continue
+		}
+		if corner[axis] > 0 {
+			sides[side][axis] = 1
+		} else {
+			sides[side][axis] = -1
+		}
+		side++
+	}
 
-					chunk = append(chunk,
-						// pos
-						pos.X(), pos.Y(), pos.Z(),
+	opaque := func(o ...[3]int) int {
+		x, y, z := i+n[0], j+n[1], k+n[2]
+		for _, d := range o {
+			x, y, z = x+d[0], y+d[1], z+d[2]
+		}
+		if b := c.blockAt(x, y, z); b != nil && b.Opaque() {
+			return 1
+		}
+		return 0
+	}
 
-						// norm vector
-						vert.norm.X(), vert.norm.Y(), vert.norm.Z(),
+	side1, side2 := opaque(sides[0]), opaque(sides[1])
+	if side1 == 1 && side2 == 1 {
+		return 0
+	}
+	return 3 - side1 - side2 - opaque(sides[0], sides[1])
+}
 
-						// texture
-						vert.tex.X(), vert.tex.Y(),
-					)
+// Returns the section at index s, creating it filled with inactive blocks if needed.
+// The blocks of a new section are lit by the sky like the missing section was.
+func (c *Chunk) section(s int) *ChunkSection {
+	if c.sections[s] != nil {
+		return c.sections[s]
+	}
 
-					chunkDepth = append(chunkDepth,
-						// only position
-						pos.X(), pos.Y(), pos.Z(),
-					)
-				}
+	section := &ChunkSection{chunk: c, y: s * chunkSectionSize, dirty: true}
+	for i := range chunkWidth {
+		for k := range chunkWidth {
+			sky, _ := c.lightAt(i, section.y, k)
+			for j := range chunkSectionSize {
+				b := newBlock(c, i, section.y+j, k, "bedrock")
+				b.skyLight = uint8(sky)
+				section.blocks[i][j][k] = b
 			}
 		}
 	}
+	c.sections[s] = section
+	return section
+}
 
-	// send vertices to gpu
-	if len(chunk) > 0 {
-		gl.BindBuffer(gl.ARRAY_BUFFER, c.vbo)
-		gl.BufferData(gl.ARRAY_BUFFER, len(chunk)*4, gl.Ptr(chunk), gl.DYNAMIC_DRAW)
+// Marks the section of the block at height j to be rebuffered, as well as the
+// neighbouring section if the block is on its border since the faces between them change.
+func (c *Chunk) Invalidate(j int) {
+	for _, y := range []int{j, j - 1, j + 1} {
+		if y < 0 || y >= c.Height() {
+			continue
+		}
+		if s := c.sections[y/chunkSectionSize]; s != nil {
+			s.dirty = true
+		}
+	}
+}
 
-		gl.BindBuffer(gl.ARRAY_BUFFER, c.shadowVbo)
-		gl.BufferData(gl.ARRAY_BUFFER, len(chunkDepth)*4, gl.Ptr(chunkDepth), gl.DYNAMIC_DRAW)
+// Deletes buffers from gpu.
+func (c *Chunk) Destroy() {
+	for _, s := range c.sections {
+		if s != nil {
+			s.Destroy()
+		}
 	}
 }
 
-// Draws the chunk with vertices for the depth map.
-func (c *Chunk) DrawDepthMap(light *Light) {
+// Sends the vertices of the changed sections to GPU.
+// Sections left without active blocks are dropped.
+func (c *Chunk) Buffer() {
+	for idx, s := range c.sections {
+		if s == nil || !s.dirty {
+			continue
+		}
+
+		if s.Empty() {
+			s.Destroy()
+			c.sections[idx] = nil
+			continue
+		}
+		s.Buffer()
+	}
+}
+
+// Draws the chunk with vertices for a cascade of the depth map, seen through its light matrix.
+func (c *Chunk) DrawDepthMap(lightMat mgl32.Mat4) {
 	gl.UseProgram(c.shadowMapShader.handle)
-	gl.BindVertexArray(c.shadowVao)
 
 	model := mgl32.Translate3D(c.pos.X(), c.pos.Y(), c.pos.Z())
 	modelUniform := gl.GetUniformLocation(c.shadowMapShader.handle, gl.Str("model\x00"))
 	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
 
-	lightMat := light.Mat()
 	lightMatUniform := gl.GetUniformLocation(c.shadowMapShader.handle, gl.Str("lightSpaceMatrix\x00"))
 	gl.UniformMatrix4fv(lightMatUniform, 1, false, &lightMat[0])
 
-	gl.DrawArrays(gl.TRIANGLES, 0, int32(c.vertCount))
+	for _, s := range c.sections {
+		if s != nil && s.vertCount > 0 {
+			gl.BindVertexArray(s.shadowVao)
+			gl.DrawArrays(gl.TRIANGLES, 0, int32(s.vertCount))
+		}
+	}
 }
 
-// Draws the chunk from the perspective of the provided camera.
+// Draws the visible sections of the chunk from the perspective of the provided camera.
 // Sets the "lookedAtBlock" to be the provided target block.
 func (c *Chunk) Draw(target *TargetBlock, camera *Camera, light *Light, depthMap *DepthMap) {
+	c.setUniforms(target, camera, light, depthMap)
+	for _, s := range c.sections {
+		if s != nil && s.visible && s.vertCount > 0 {
+			gl.BindVertexArray(s.vao)
+			gl.DrawArrays(gl.TRIANGLES, 0, int32(s.vertCount))
+		}
+	}
+}
+
+// Draws the translucent blocks of the visible sections of the chunk, from the farthest section to the nearest.
+// Should be called after the opaque blocks of every chunk are drawn, with blending enabled.
+func (c *Chunk) DrawTranslucent(camera *Camera, light *Light, depthMap *DepthMap) {
+	sections := make([]*ChunkSection, 0, len(c.sections))
+	for _, s := range c.sections {
+		if s != nil && s.visible && s.translucentVertCount > 0 {
+			sections = append(sections, s)
+		}
+	}
+	slices.SortFunc(sections, func(a, b *ChunkSection) int {
+		return cmp.Compare(c.SectionBox(b.y/chunkSectionSize).Distance(camera.pos), c.SectionBox(a.y/chunkSectionSize).Distance(camera.pos))
+	})
+
+	uniformsSet := false
+	for _, s := range sections {
+
+		if !uniformsSet {
+			c.setUniforms(nil, camera, light, depthMap)
+			uniformsSet = true
+		}
+		gl.BindVertexArray(s.translucentVao)
+		gl.DrawArrays(gl.TRIANGLES, 0, int32(s.translucentVertCount))
+	}
+}
+
+// Uses the chunk shader and attaches the uniforms shared by the passes.
+func (c *Chunk) setUniforms(target *TargetBlock, camera *Camera, light *Light, depthMap *DepthMap) {
 	gl.UseProgram(c.shader.handle)
-	gl.BindVertexArray(c.vao)
 
 	// build model without view (model translates to world position)
 	model := mgl32.Translate3D(c.pos.X(), c.pos.Y(), c.pos.Z())
@@ -227,6 +387,10 @@ func (c *Chunk) Draw(target *TargetBlock, camera *Camera, light *Light, depthMap
 	viewPosUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("cameraPos\x00"))
 	gl.Uniform3fv(viewPosUniform, 1, &camera.pos[0])
 
+	// attach view direction, the distance along it selects the shadow cascade
+	viewDirUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("cameraDir\x00"))
+	gl.Uniform3fv(viewDirUniform, 1, &camera.view[0])
+
 	// attach world light position
 	lightPosUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("lightPos\x00"))
 	gl.Uniform3fv(lightPosUniform, 1, &light.pos[0])
@@ -235,6 +399,21 @@ func (c *Chunk) Draw(target *TargetBlock, camera *Camera, light *Light, depthMap
 	lightLvlUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("lightLevel\x00"))
 	gl.Uniform1f(lightLvlUniform, light.level)
 
+	// attach the fog, distant fragments fade into the horizon color
+	fogColorUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("fogColor\x00"))
+	gl.Uniform3fv(fogColorUniform, 1, &light.horizon[0])
+
+	fogStartUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("fogStart\x00"))
+	gl.Uniform1f(fogStartUniform, fogStart)
+
+	fogEndUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("fogEnd\x00"))
+	gl.Uniform1f(fogEndUniform, fogEnd)
+
+	// attach how far the chunk faded in since it was spawned
+	fade := min(float32(time.Since(c.spawned))/float32(chunkFadeDuration), 1)
+	fadeUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("fade\x00"))
+	gl.Uniform1f(fadeUniform, fade)
+
 	// attach lookedAtBlock which determines which block is being locked at in the chunk
 	isLooking := 0
 	lookedAtBlockUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("lookedAtBlock\x00"))
@@ -248,26 +427,313 @@ func (c *Chunk) Draw(target *TargetBlock, camera *Camera, light *Light, depthMap
 	isLookingUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("isLooking\x00"))
 	gl.Uniform1i(isLookingUniform, int32(isLooking))
 
-	lightMat := light.Mat()
-	lightMatUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("lightSpaceMatrix\x00"))
-	gl.UniformMatrix4fv(lightMatUniform, 1, false, &lightMat[0])
+	// attach the shadow cascades
+	cascades := len(depthMap.cascades)
+	lightMats := make([]mgl32.Mat4, cascades)
+	fars := make([]float32, cascades)
+	texelSizes := make([]float32, cascades)
+	for i, cascade := range depthMap.cascades {
+		lightMats[i] = cascade.mat
+		fars[i] = cascade.far
+		texelSizes[i] = cascade.texelSize
+	}
+
+	lightMatsUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("lightSpaceMatrices\x00"))
+	gl.UniformMatrix4fv(lightMatsUniform, int32(cascades), false, &lightMats[0][0])
+
+	farsUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("cascadeFar\x00"))
+	gl.Uniform1fv(farsUniform, int32(cascades), &fars[0])
+
+	texelSizesUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("cascadeTexelSize\x00"))
+	gl.Uniform1fv(texelSizesUniform, int32(cascades), &texelSizes[0])
+
+	cascadeCountUniform := gl.GetUniformLocation(c.shader.handle, gl.Str("cascadeCount\x00"))
+	gl.Uniform1i(cascadeCountUniform, int32(cascades))
 
 	gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindTexture(gl.TEXTURE_2D, c.atlas.texture.handle)
 
 	gl.ActiveTexture(gl.TEXTURE1)
-	gl.BindTexture(gl.TEXTURE_2D, depthMap.texture)
-
-	// final draw call for chunk
-	gl.DrawArrays(gl.TRIANGLES, 0, int32(c.vertCount))
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, depthMap.texture)
 }
 
 // Returns a box around the chunk.
 func (c *Chunk) Box() Box {
 	max := c.pos.Add(mgl32.Vec3{
 		chunkWidth,
-		chunkHeight,
+		float32(c.Height()),
 		chunkWidth,
 	})
 	return newBox(c.pos, max)
 }
+
+// Returns the box around the section at index s of the chunk, which may have no blocks.
+func (c *Chunk) SectionBox(s int) Box {
+	min := c.pos.Add(mgl32.Vec3{0, float32(s * chunkSectionSize), 0})
+	return newBox(min, min.Add(mgl32.Vec3{chunkWidth, chunkSectionSize, chunkWidth}))
+}
+
+// Initialize the section buffers on the GPU.
+func (s *ChunkSection) Init() {
+	shader := s.chunk.shader
+	gl.UseProgram(shader.handle)
+
+	// gen vao and vbo
+	gl.GenVertexArrays(1, &s.vao)
+	gl.BindVertexArray(s.vao)
+	gl.GenBuffers(1, &s.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+	s.configureAttributes()
+
+	// translucent blocks have the same attributes in their own buffer
+	gl.GenVertexArrays(1, &s.translucentVao)
+	gl.BindVertexArray(s.translucentVao)
+	gl.GenBuffers(1, &s.translucentVbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.translucentVbo)
+	s.configureAttributes()
+
+	textureUniform := gl.GetUniformLocation(shader.handle, gl.Str("tex\x00"))
+	gl.Uniform1i(textureUniform, 0)
+
+	shadowMapUniform := gl.GetUniformLocation(shader.handle, gl.Str("shadowMap\x00"))
+	gl.Uniform1i(shadowMapUniform, 1)
+
+	// shadow map pass
+	gl.GenVertexArrays(1, &s.shadowVao)
+	gl.BindVertexArray(s.shadowVao)
+	gl.GenBuffers(1, &s.shadowVbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.shadowVbo)
+	vertAttribShadow := uint32(gl.GetAttribLocation(s.chunk.shadowMapShader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttribShadow)
+	gl.VertexAttribPointerWithOffset(vertAttribShadow, 3, gl.FLOAT, false, 3*4, 0)
+}
+
+// Configures the vertex attributes of the bound vao and vbo for the chunk shader.
+func (s *ChunkSection) configureAttributes() {
+	shader := s.chunk.shader
+	vertAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 3, gl.FLOAT, false, 12*4, 0)
+
+	// configure the attributes
+	normAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("normal\x00")))
+	gl.EnableVertexAttribArray(normAttrib)
+	gl.VertexAttribPointerWithOffset(normAttrib, 3, gl.FLOAT, false, 12*4, 3*4)
+
+	texAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("texCoord\x00")))
+	gl.EnableVertexAttribArray(texAttrib)
+	gl.VertexAttribPointerWithOffset(texAttrib, 2, gl.FLOAT, false, 12*4, 6*4)
+
+	lightAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("light\x00")))
+	gl.EnableVertexAttribArray(lightAttrib)
+	gl.VertexAttribPointerWithOffset(lightAttrib, 2, gl.FLOAT, false, 12*4, 8*4)
+
+	aoAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("ao\x00")))
+	gl.EnableVertexAttribArray(aoAttrib)
+	gl.VertexAttribPointerWithOffset(aoAttrib, 1, gl.FLOAT, false, 12*4, 10*4)
+
+	emissionAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("emission\x00")))
+	gl.EnableVertexAttribArray(emissionAttrib)
+	gl.VertexAttribPointerWithOffset(emissionAttrib, 1, gl.FLOAT, false, 12*4, 11*4)
+}
+
+// Deletes buffers from gpu.
+func (s *ChunkSection) Destroy() {
+	if s.vao == 0 {
+		return
+	}
+
+	gl.DeleteBuffers(1, &s.vbo)
+	s.vbo = 0
+	gl.DeleteVertexArrays(1, &s.vao)
+	s.vao = 0
+
+	gl.DeleteBuffers(1, &s.shadowVbo)
+	s.shadowVbo = 0
+	gl.DeleteVertexArrays(1, &s.shadowVao)
+	s.shadowVao = 0
+
+	gl.DeleteBuffers(1, &s.translucentVbo)
+	s.translucentVbo = 0
+	gl.DeleteVertexArrays(1, &s.translucentVao)
+	s.translucentVao = 0
+}
+
+// Returns true if the section has no active block.
+func (s *ChunkSection) Empty() bool {
+	for _, layer := range s.blocks {
+		for _, row := range layer {
+			for _, block := range row {
+				if block.active {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Sends the section vertices to GPU.
+// Faces are hidden by the blocks of the sections above and below
+// and lit by the sky and block light of the block in front of them.
+// Face corners are darkened by the opaque blocks around them (ambient occlusion),
+// quads are split along their brightest diagonal so the occlusion is interpolated evenly.
+// Faces are only hidden by opaque blocks, see FaceHidden.
+// Translucent blocks go to their own buffer and don't cast shadows.
+func (s *ChunkSection) Buffer() {
+	c := s.chunk
+	s.dirty = false
+	s.computeConnections()
+
+	// reset vertCount
+	s.vertCount = 0
+	s.translucentVertCount = 0
+
+	// start building chunk
+	chunk := make([]float32, 0)
+	chunkDepth := make([]float32, 0)
+	translucent := make([]float32, 0)
+	for i, layer := range s.blocks {
+		for _, row := range layer {
+			for k, block := range row {
+				if !block.active {
+					continue
+				}
+				j := block.j
+
+				// returns true if the block at i,j,k is the same fluid as this block
+				fluid := block.Fluid()
+				sameFluid := func(i, j, k int) bool {
+					b := c.existingBlock(i, j, k)
+					return fluid != nil && b != nil && b.Fluid() != nil && b.blockType == block.blockType
+				}
+
+				// get vertices for visible faces only
+				var excludeFaces [6]bool
+				checkExclude := func(i, j, k int, face Direction) {
+					b := c.existingBlock(i, j, k)
+					if b == nil {
+						return
+					}
+
+					if block.FaceHidden(b) {
+						excludeFaces[face] = true
+					}
+				}
+				checkExclude(i, j, k+1, south)
+				checkExclude(i, j, k-1, north)
+				checkExclude(i, j+1, k, up)
+				checkExclude(i, j-1, k, down)
+				checkExclude(i-1, j, k, west)
+				checkExclude(i+1, j, k, east)
+
+				// the surface of a fluid is lowered by its level unless more of the fluid is above
+				height := float32(1)
+				if fluid != nil && !sameFluid(i, j+1, k) {
+					height = block.FluidHeight()
+				}
+
+				// light of the visible faces and of the faces inside the block, normalized
+				var light [6][2]float32
+				for d, o := range lightOffsets {
+					if !excludeFaces[d] {
+						sky, blockLight := c.lightAt(i+o[0], j+o[1], k+o[2])
+						light[d] = [2]float32{float32(sky) / maxLightLevel, float32(blockLight) / maxLightLevel}
+					}
+				}
+				innerLight := [2]float32{float32(block.skyLight) / maxLightLevel, float32(block.blockLight) / maxLightLevel}
+
+				// only cubes are occluded, the faces of other shapes don't line up with the corners of the blocks
+				cube := block.Shape() == cubeShape
+
+				// emitting blocks glow with the bloom
+				emission := float32(block.Emission()) / maxLightLevel
+
+				// translate vertices to respective pos in chunk
+				translate := block.Translate()
+				layer := block.RenderLayer()
+				verts := block.Vertices(excludeFaces)
+				for f := 0; f < len(verts); f += len(quadIndices) {
+					quad := verts[f : f+len(quadIndices)]
+
+					// occlusion of each corner, the quad is flipped when the diagonal from corner 0 to 4 is brighter
+					var ao [len(quadIndices)]float32
+					for v, vert := range quad {
+						ao[v] = 1
+						if cube {
+							ao[v] = float32(c.cornerOcclusion(i, j, k, vert.face, vert.pos)) / 3
+						}
+					}
+					order := quadIndices
+					if ao[0]+ao[4] > ao[1]+ao[2] {
+						order = flippedQuadIndices
+					}
+
+					for _, corner := range order {
+						vert := quad[corner]
+						vert.pos[1] = (vert.pos.Y()+0.5)*height - 0.5
+						faceLight := light[vert.face]
+						if vert.inner {
+							faceLight = innerLight
+						}
+						pos := translate.Mul4x1(vert.pos.Vec4(1))
+						v := []float32{
+							// pos
+							pos.X(), pos.Y(), pos.Z(),
+
+							// norm vector
+							vert.norm.X(), vert.norm.Y(), vert.norm.Z(),
+
+							// texture
+							vert.tex.X(), vert.tex.Y(),
+
+							// sky and block light
+							faceLight[0], faceLight[1],
+
+							// ambient occlusion
+							ao[corner],
+
+							// emitted light
+							emission,
+						}
+
+						if layer == translucentLayer {
+							s.translucentVertCount++
+							translucent = append(translucent, v...)
+							continue
+						}
+
+						s.vertCount++
+						chunk = append(chunk, v...)
+						chunkDepth = append(chunkDepth,
+							// only position
+							pos.X(), pos.Y(), pos.Z(),
+						)
+					}
+				}
+			}
+		}
+	}
+
+	if len(chunk) == 0 && len(translucent) == 0 {
+		return
+	}
+
+	if s.vao == 0 {
+		s.Init()
+	}
+
+	// send vertices to gpu
+	if len(chunk) > 0 {
+		gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+		gl.BufferData(gl.ARRAY_BUFFER, len(chunk)*4, gl.Ptr(chunk), gl.DYNAMIC_DRAW)
+
+		gl.BindBuffer(gl.ARRAY_BUFFER, s.shadowVbo)
+		gl.BufferData(gl.ARRAY_BUFFER, len(chunkDepth)*4, gl.Ptr(chunkDepth), gl.DYNAMIC_DRAW)
+	}
+
+	if len(translucent) > 0 {
+		gl.BindBuffer(gl.ARRAY_BUFFER, s.translucentVbo)
+		gl.BufferData(gl.ARRAY_BUFFER, len(translucent)*4, gl.Ptr(translucent), gl.DYNAMIC_DRAW)
+	}
+}
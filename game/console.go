@@ -0,0 +1,141 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Console lets the player type commands at the bottom left of the screen.
+// It opens with the slash key, enter runs the typed command and escape closes it.
+type Console struct {
+	text *TextRenderer
+
+	// if the console reads the typed keys
+	open bool
+
+	// command being typed
+	input []rune
+
+	// result of the last command and when it ran, shown for a while after the console closes
+	message     string
+	messageTime time.Time
+
+	// commands by name
+	commands map[string]*Command
+}
+
+// Command run from the console with the words typed after its name.
+// Returns the message shown to the player, errors are shown with the usage.
+type Command struct {
+	usage string
+	run   func(args []string) (string, error)
+}
+
+const (
+	consoleTextSize        = 20
+	consoleMargin          = 10
+	consoleMessageDuration = 5 * time.Second
+)
+
+func newConsole(text *TextRenderer) *Console {
+	c := &Console{
+		text:     text,
+		commands: make(map[string]*Command),
+	}
+	return c
+}
+
+// Adds a command run by typing its name.
+func (c *Console) Register(name string, command *Command) {
+	c.commands[name] = command
+}
+
+// Opens the console with an empty command.
+func (c *Console) Open() {
+	c.open = true
+	c.input = c.input[:0]
+}
+
+// Closes the console, dropping the typed command.
+func (c *Console) Close() {
+	c.open = false
+}
+
+// Types a character in the command if the console is open.
+func (c *Console) Type(char rune) {
+	if c.open {
+		c.input = append(c.input, char)
+	}
+}
+
+// Handles a key pressed while the console is open.
+func (c *Console) Key(key glfw.Key, action glfw.Action) {
+	if action == glfw.Release {
+		return
+	}
+
+	switch key {
+	case glfw.KeyBackspace:
+		if len(c.input) > 0 {
+			c.input = c.input[:len(c.input)-1]
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		if action == glfw.Press {
+			c.Run(string(c.input))
+			c.Close()
+		}
+	case glfw.KeyEscape:
+		c.Close()
+	}
+}
+
+// Runs a command line, the leading slash is optional.
+func (c *Console) Run(line string) {
+	fields := strings.Fields(strings.TrimPrefix(line, "/"))
+	if len(fields) == 0 {
+		return
+	}
+
+	command, ok := c.commands[fields[0]]
+	if !ok {
+		names := make([]string, 0, len(c.commands))
+		for name := range c.commands {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		c.show(fmt.Sprintf("unknown command %q, try: %s", fields[0], strings.Join(names, ", ")))
+		return
+	}
+
+	message, err := command.run(fields[1:])
+	if err != nil {
+		message = fmt.Sprintf("%s (usage: %s)", err, command.usage)
+	}
+	c.show(message)
+}
+
+// Shows a message under the console.
+func (c *Console) show(message string) {
+	log.Println(message)
+	c.message = message
+	c.messageTime = time.Now()
+}
+
+// Adds the console text to the text renderer: the typed command while open and the last message for a while.
+func (c *Console) Add() {
+	y := float32(windowHeight - consoleMargin - consoleTextSize)
+	if c.open {
+		c.text.Add(string(c.input)+"_", mgl32.Vec2{consoleMargin, y}, consoleTextSize, alignLeft, textWhite)
+		y -= consoleTextSize * textLineSpacing
+	}
+
+	if c.message != "" && time.Since(c.messageTime) < consoleMessageDuration {
+		c.text.Add(c.message, mgl32.Vec2{consoleMargin, y}, consoleTextSize, alignLeft, textYellow)
+	}
+}
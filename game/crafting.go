@@ -0,0 +1,335 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RecipeBook holds the crafting recipes loaded from a directory of json files.
+// Each file holds a list of recipes following the format:
+//
+//	[
+//	  {"type": "shapeless", "ingredients": ["wood"], "result": {"block": "planks", "count": 4}},
+//	  {"type": "shaped", "pattern": ["SS", "SS"], "key": {"S": "stone"}, "result": {"block": "stone-bricks", "count": 4}}
+//	]
+//
+// Spaces in a pattern are empty cells.
+type RecipeBook struct {
+	recipes  []*Recipe
+	rootPath string
+}
+
+// Recipe transforms the blocks in the crafting grid into a result.
+type Recipe struct {
+	// file the recipe was loaded from
+	source string
+
+	// shaped recipes match the pattern anywhere in the grid, shapeless only match the ingredients
+	shaped bool
+
+	// rows of block types ("" for empty cells) for shaped recipes
+	pattern [][]string
+
+	// sorted block types for shapeless recipes
+	ingredients []string
+
+	result ItemStack
+}
+
+// Block types placed in each cell of the crafting grid ("" for empty cells).
+type CraftingInput [craftingGridSize][craftingGridSize]string
+
+// Crafting grid holding the stacks placed by the player.
+type CraftingGrid struct {
+	book  *RecipeBook
+	slots [craftingGridSize * craftingGridSize]ItemStack
+}
+
+// Format of a recipe in the json files.
+type recipeDefinition struct {
+	Type        string            `json:"type"`
+	Pattern     []string          `json:"pattern"`
+	Key         map[string]string `json:"key"`
+	Ingredients []string          `json:"ingredients"`
+	Result      struct {
+		Block string `json:"block"`
+		Count int    `json:"count"`
+	} `json:"result"`
+}
+
+const craftingGridSize = 3
+
+func newRecipeBook(root string) *RecipeBook {
+	r := &RecipeBook{}
+	r.rootPath = root
+	r.init()
+	return r
+}
+
+// Loads and validates the recipes found in the rootPath.
+func (r *RecipeBook) init() {
+	files, err := filepath.Glob(filepath.Join(r.rootPath, "*.json"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		var defs []recipeDefinition
+		if err := json.Unmarshal(b, &defs); err != nil {
+			log.Panicf("invalid recipe file %s: %v", file, err)
+		}
+
+		for i, def := range defs {
+			recipe, err := parseRecipe(def)
+			if err != nil {
+				log.Panicf("invalid recipe %d in %s: %v", i, file, err)
+			}
+			recipe.source = file
+			r.recipes = append(r.recipes, recipe)
+		}
+	}
+
+	log.Printf("Loaded %d recipes", len(r.recipes))
+}
+
+// Returns the first recipe that matches the input or nil.
+func (r *RecipeBook) Match(input CraftingInput) *Recipe {
+	for _, recipe := range r.recipes {
+		if recipe.Matches(input) {
+			return recipe
+		}
+	}
+	return nil
+}
+
+// Converts a recipe definition to a recipe, validating block types against the block registry.
+func parseRecipe(def recipeDefinition) (*Recipe, error) {
+	valid := func(blockType string) error {
+		if _, ok := blocks[blockType]; !ok {
+			return fmt.Errorf("unknown block type %q", blockType)
+		}
+		return nil
+	}
+
+	if err := valid(def.Result.Block); err != nil {
+		return nil, err
+	}
+	if def.Result.Count < 1 || def.Result.Count > stackSize(def.Result.Block) {
+		return nil, fmt.Errorf("invalid result count %d", def.Result.Count)
+	}
+
+	recipe := &Recipe{
+		result: ItemStack{def.Result.Block, def.Result.Count},
+	}
+
+	switch def.Type {
+	case "shaped":
+		recipe.shaped = true
+		if len(def.Pattern) == 0 || len(def.Pattern) > craftingGridSize {
+			return nil, fmt.Errorf("pattern must have 1 to %d rows", craftingGridSize)
+		}
+
+		width := len(def.Pattern[0])
+		for _, row := range def.Pattern {
+			if len(row) != width || width == 0 || width > craftingGridSize {
+				return nil, fmt.Errorf("pattern rows must have the same length of 1 to %d", craftingGridSize)
+			}
+
+			cells := make([]string, 0, width)
+			for _, c := range row {
+				if c == ' ' {
+					cells = append(cells, "")
+					continue
+				}
+
+				blockType, ok := def.Key[string(c)]
+				if !ok {
+					return nil, fmt.Errorf("pattern symbol %q is not in key", c)
+				}
+				if err := valid(blockType); err != nil {
+					return nil, err
+				}
+				cells = append(cells, blockType)
+			}
+			recipe.pattern = append(recipe.pattern, cells)
+		}
+
+		trimmed := trimInput(recipe.pattern)
+		if trimmed[0][0] == "" && len(trimmed) == 1 && len(trimmed[0]) == 1 {
+			return nil, fmt.Errorf("pattern is empty")
+		}
+		if len(trimmed) != len(recipe.pattern) || len(trimmed[0]) != width {
+			return nil, fmt.Errorf("pattern has empty border rows or columns")
+		}
+	case "shapeless":
+		if len(def.Ingredients) == 0 || len(def.Ingredients) > craftingGridSize*craftingGridSize {
+			return nil, fmt.Errorf("shapeless recipe must have 1 to %d ingredients", craftingGridSize*craftingGridSize)
+		}
+
+		for _, blockType := range def.Ingredients {
+			if err := valid(blockType); err != nil {
+				return nil, err
+			}
+		}
+		recipe.ingredients = slices.Sorted(slices.Values(def.Ingredients))
+	default:
+		return nil, fmt.Errorf("unknown recipe type %q", def.Type)
+	}
+
+	return recipe, nil
+}
+
+// Returns true if the recipe can be crafted from the input.
+// Shaped recipes also match when mirrored horizontally.
+func (r *Recipe) Matches(input CraftingInput) bool {
+	if !r.shaped {
+		cells := []string{}
+		for _, row := range input {
+			for _, c := range row {
+				if c != "" {
+					cells = append(cells, c)
+				}
+			}
+		}
+		slices.Sort(cells)
+		return slices.Equal(cells, r.ingredients)
+	}
+
+	rows := make([][]string, 0, craftingGridSize)
+	for _, row := range input {
+		rows = append(rows, row[:])
+	}
+	trimmed := trimInput(rows)
+	if len(trimmed) != len(r.pattern) || len(trimmed[0]) != len(r.pattern[0]) {
+		return false
+	}
+
+	same, mirrored := true, true
+	for i, row := range r.pattern {
+		for j, c := range row {
+			same = same && trimmed[i][j] == c
+			mirrored = mirrored && trimmed[i][len(row)-1-j] == c
+		}
+	}
+	return same || mirrored
+}
+
+// Returns a readable description of the recipe.
+func (r *Recipe) String() string {
+	var in string
+	if r.shaped {
+		rows := []string{}
+		for _, row := range r.pattern {
+			rows = append(rows, strings.Join(row, ","))
+		}
+		in = strings.Join(rows, "/")
+	} else {
+		in = strings.Join(r.ingredients, "+")
+	}
+	return fmt.Sprintf("%s -> %d %s", in, r.result.count, r.result.blockType)
+}
+
+// Removes the empty rows and columns around the used cells.
+// Returns a single empty cell if nothing is used.
+func trimInput(rows [][]string) [][]string {
+	minI, maxI, minJ, maxJ := len(rows), -1, len(rows[0]), -1
+	for i, row := range rows {
+		for j, c := range row {
+			if c == "" {
+				continue
+			}
+			minI, maxI = min(minI, i), max(maxI, i)
+			minJ, maxJ = min(minJ, j), max(maxJ, j)
+		}
+	}
+
+	if maxI < 0 {
+		return [][]string{{""}}
+	}
+
+	out := [][]string{}
+	for i := minI; i <= maxI; i++ {
+		out = append(out, rows[i][minJ:maxJ+1])
+	}
+	return out
+}
+
+func newCraftingGrid(book *RecipeBook) *CraftingGrid {
+	return &CraftingGrid{
+		book: book,
+	}
+}
+
+// Returns the stack in a cell of the grid.
+func (c *CraftingGrid) Slot(s int) ItemStack {
+	return c.slots[s]
+}
+
+// Sets the stack in a cell of the grid.
+func (c *CraftingGrid) SetSlot(s int, stack ItemStack) {
+	if stack.Empty() {
+		stack = ItemStack{}
+	}
+	c.slots[s] = stack
+}
+
+// Returns the block types placed in the grid.
+func (c *CraftingGrid) Input() CraftingInput {
+	var input CraftingInput
+	for s, stack := range c.slots {
+		input[s/craftingGridSize][s%craftingGridSize] = stack.blockType
+	}
+	return input
+}
+
+// Returns what the grid currently crafts, empty if no recipe matches.
+func (c *CraftingGrid) Result() ItemStack {
+	recipe := c.book.Match(c.Input())
+	if recipe == nil {
+		return ItemStack{}
+	}
+	return recipe.result
+}
+
+// Crafts the result once, consuming one block of every used cell.
+func (c *CraftingGrid) Craft() ItemStack {
+	recipe := c.book.Match(c.Input())
+	if recipe == nil {
+		return ItemStack{}
+	}
+
+	for s, stack := range c.slots {
+		if !stack.Empty() {
+			stack.count--
+			c.SetSlot(s, stack)
+		}
+	}
+
+	log.Println("Crafted", recipe)
+	return recipe.result
+}
+
+// Moves the content of the grid back to the inventory.
+// The grid isn't saved, so whatever doesn't fit is dropped.
+func (c *CraftingGrid) Clear(inventory *Inventory, drop func(stack ItemStack)) {
+	for s, stack := range c.slots {
+		if stack.Empty() {
+			continue
+		}
+
+		if left := inventory.Add(stack.blockType, stack.count); left > 0 {
+			drop(ItemStack{stack.blockType, left})
+		}
+		c.SetSlot(s, ItemStack{})
+	}
+}
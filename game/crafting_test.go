@@ -0,0 +1,168 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Returns a recipe definition with the result block and count.
+func recipeDef(typ string, pattern []string, key map[string]string, ingredients []string, block string, count int) recipeDefinition {
+	def := recipeDefinition{Type: typ, Pattern: pattern, Key: key, Ingredients: ingredients}
+	def.Result.Block = block
+	def.Result.Count = count
+	return def
+}
+
+func TestParseRecipe(t *testing.T) {
+	tests := []struct {
+		name string
+		def  recipeDefinition
+		err  string
+	}{
+		{"shaped", recipeDef("shaped", []string{"SS", "SS"}, map[string]string{"S": "stone"}, nil, "stone-bricks", 4), ""},
+		{"shaped with holes", recipeDef("shaped", []string{"S S", " S "}, map[string]string{"S": "stone"}, nil, "stone", 1), ""},
+		{"shapeless", recipeDef("shapeless", nil, nil, []string{"wood"}, "planks", 4), ""},
+		{"unknown result", recipeDef("shapeless", nil, nil, []string{"wood"}, "unobtainium", 1), "unknown block type"},
+		{"unknown ingredient", recipeDef("shapeless", nil, nil, []string{"unobtainium"}, "planks", 1), "unknown block type"},
+		{"unknown key block", recipeDef("shaped", []string{"S"}, map[string]string{"S": "unobtainium"}, nil, "planks", 1), "unknown block type"},
+		{"symbol not in key", recipeDef("shaped", []string{"SX"}, map[string]string{"S": "stone"}, nil, "planks", 1), "not in key"},
+		{"zero count", recipeDef("shapeless", nil, nil, []string{"wood"}, "planks", 0), "invalid result count"},
+		{"count over stack size", recipeDef("shapeless", nil, nil, []string{"wood"}, "planks", stackSize("planks")+1), "invalid result count"},
+		{"empty top row", recipeDef("shaped", []string{"  ", "SS"}, map[string]string{"S": "stone"}, nil, "stone", 1), "empty border"},
+		{"empty bottom row", recipeDef("shaped", []string{"SS", "  "}, map[string]string{"S": "stone"}, nil, "stone", 1), "empty border"},
+		{"empty left column", recipeDef("shaped", []string{" S", " S"}, map[string]string{"S": "stone"}, nil, "stone", 1), "empty border"},
+		{"empty right column", recipeDef("shaped", []string{"S ", "S "}, map[string]string{"S": "stone"}, nil, "stone", 1), "empty border"},
+		{"empty pattern", recipeDef("shaped", []string{" "}, map[string]string{}, nil, "stone", 1), "empty"},
+		{"too many rows", recipeDef("shaped", []string{"S", "S", "S", "S"}, map[string]string{"S": "stone"}, nil, "stone", 1), "rows"},
+		{"uneven rows", recipeDef("shaped", []string{"SS", "S"}, map[string]string{"S": "stone"}, nil, "stone", 1), "same length"},
+		{"no ingredients", recipeDef("shapeless", nil, nil, nil, "planks", 1), "ingredients"},
+		{"unknown type", recipeDef("smelting", nil, nil, []string{"wood"}, "planks", 1), "unknown recipe type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRecipe(tt.def)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRecipeMatches(t *testing.T) {
+	mustParse := func(def recipeDefinition) *Recipe {
+		r, err := parseRecipe(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	bricks := mustParse(recipeDef("shaped", []string{"SS", "SS"}, map[string]string{"S": "stone"}, nil, "stone-bricks", 4))
+	stairs := mustParse(recipeDef("shaped", []string{"P  ", "PP ", "PPP"}, map[string]string{"P": "planks"}, nil, "planks-stairs", 4))
+	mixed := mustParse(recipeDef("shaped", []string{"SD"}, map[string]string{"S": "stone", "D": "dirt"}, nil, "cobblestone", 1))
+	planks := mustParse(recipeDef("shapeless", nil, nil, []string{"wood"}, "planks", 4))
+	pair := mustParse(recipeDef("shapeless", nil, nil, []string{"sand", "dirt"}, "gravel", 2))
+
+	tests := []struct {
+		name   string
+		recipe *Recipe
+		input  CraftingInput
+		want   bool
+	}{
+		{"shaped top left", bricks, CraftingInput{{"stone", "stone", ""}, {"stone", "stone", ""}, {"", "", ""}}, true},
+		{"shaped offset", bricks, CraftingInput{{"", "", ""}, {"", "stone", "stone"}, {"", "stone", "stone"}}, true},
+		{"shaped missing cell", bricks, CraftingInput{{"stone", "stone", ""}, {"stone", "", ""}, {"", "", ""}}, false},
+		{"shaped extra cell", bricks, CraftingInput{{"stone", "stone", "stone"}, {"stone", "stone", ""}, {"", "", ""}}, false},
+		{"shaped wrong block", bricks, CraftingInput{{"stone", "dirt", ""}, {"stone", "stone", ""}, {"", "", ""}}, false},
+		{"asymmetric shape", stairs, CraftingInput{{"planks", "", ""}, {"planks", "planks", ""}, {"planks", "planks", "planks"}}, true},
+		{"mirrored shape", stairs, CraftingInput{{"", "", "planks"}, {"", "planks", "planks"}, {"planks", "planks", "planks"}}, true},
+		{"flipped upside down", stairs, CraftingInput{{"planks", "planks", "planks"}, {"planks", "planks", ""}, {"planks", "", ""}}, false},
+		{"mirrored ingredients", mixed, CraftingInput{{"", "", ""}, {"dirt", "stone", ""}, {"", "", ""}}, true},
+		{"shapeless anywhere", planks, CraftingInput{{"", "", ""}, {"", "", ""}, {"", "", "wood"}}, true},
+		{"shapeless any order", pair, CraftingInput{{"dirt", "", ""}, {"", "", ""}, {"", "sand", ""}}, true},
+		{"shapeless other order", pair, CraftingInput{{"sand", "dirt", ""}, {"", "", ""}, {"", "", ""}}, true},
+		{"shapeless extra ingredient", pair, CraftingInput{{"sand", "dirt", "dirt"}, {"", "", ""}, {"", "", ""}}, false},
+		{"shapeless missing ingredient", pair, CraftingInput{{"sand", "", ""}, {"", "", ""}, {"", "", ""}}, false},
+		{"empty grid shaped", bricks, CraftingInput{}, false},
+		{"empty grid shapeless", planks, CraftingInput{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recipe.Matches(tt.input); got != tt.want {
+				t.Fatalf("Matches(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCraftingGrid(t *testing.T) {
+	book := newRecipeBook("../recipes")
+	grid := newCraftingGrid(book)
+	if result := grid.Result(); !result.Empty() {
+		t.Fatalf("empty grid crafts %v", result)
+	}
+
+	grid.SetSlot(4, ItemStack{"wood", 2})
+	result := grid.Result()
+	if result.blockType != "planks" {
+		t.Fatalf("wood crafts %v, want planks", result)
+	}
+
+	// each craft consumes one block of the used cells
+	grid.Craft()
+	if s := grid.Slot(4); s.blockType != "wood" || s.count != 1 {
+		t.Fatalf("slot after one craft is %v, want 1 wood", s)
+	}
+	grid.Craft()
+	if s := grid.Slot(4); !s.Empty() {
+		t.Fatalf("slot after two crafts is %v, want empty", s)
+	}
+	if result := grid.Craft(); !result.Empty() {
+		t.Fatalf("empty grid crafted %v", result)
+	}
+}
+
+func TestCraftingLeftoversDroppedWhenInventoryFull(t *testing.T) {
+	g, dropped := newItemTestGame()
+	inventory := g.player.inventory
+	for s := range inventorySize {
+		inventory.SetSlot(s, ItemStack{"dirt", stackSize("dirt")})
+	}
+
+	// a crafted result held when the screen closes
+	g.crafting.SetSlot(4, ItemStack{"wood", 3})
+	g.inventoryScreen.held = g.crafting.Craft()
+	g.inventoryScreen.heldFrom = craftingOutputSlot
+
+	g.inventoryScreen.Close()
+	want := []ItemStack{{"planks", 4}, {"wood", 2}}
+	if len(*dropped) != len(want) || (*dropped)[0] != want[0] || (*dropped)[1] != want[1] {
+		t.Fatalf("dropped %v, want %v", *dropped, want)
+	}
+	if s := g.crafting.Slot(4); !s.Empty() {
+		t.Fatalf("crafting grid keeps %v after closing", s)
+	}
+}
+
+func TestCrossShapedBlocksCraftable(t *testing.T) {
+	book := newRecipeBook("../recipes")
+	crafted := map[string]bool{}
+	for _, r := range book.recipes {
+		crafted[r.result.blockType] = true
+	}
+
+	// cross shaped plants aren't generated, crafting is the only way to get them
+	for blockType, shape := range blockShapes {
+		if shape == crossShape && !crafted[blockType] {
+			t.Errorf("no recipe crafts %s", blockType)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package game
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -32,9 +34,11 @@ func newDatabase(dsn string) *Database {
 
 func (d *Database) Drop() {
 	dropTables := `
+		DROP TABLE IF EXISTS inventory_slots;
 		DROP TABLE IF EXISTS blocks;
 		DROP TABLE IF EXISTS chunks;
-		DROP TABLE IF EXISTS worlds
+		DROP TABLE IF EXISTS worlds;
+		PRAGMA user_version = 0
 	`
 
 	_, err := d.db.Exec(dropTables)
@@ -74,6 +78,15 @@ func (d *Database) Migrate() {
 		active INTEGER NOT NULL,
 		FOREIGN KEY (chunk_id) REFERENCES chunks (id),
 		PRIMARY KEY (chunk_id, i, j, k)
+	);
+
+	CREATE TABLE IF NOT EXISTS inventory_slots (
+		world_id INTEGER NOT NULL,
+		slot INTEGER NOT NULL,
+		block_type TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		FOREIGN KEY (world_id) REFERENCES worlds (id),
+		PRIMARY KEY (world_id, slot)
 	)
 	`
 
@@ -82,6 +95,43 @@ func (d *Database) Migrate() {
 		log.Fatalf("Failed to create tables: %v", err)
 		return
 	}
+
+	// apply the schema changes made after the tables were created
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+
+	for v := version; v < len(migrations); v++ {
+		log.Printf("Migrating database to version %d", v+1)
+		if _, err := d.db.Exec(migrations[v]); err != nil {
+			log.Fatalf("Failed to migrate database to version %d: %v", v+1, err)
+		}
+		if _, err := d.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", v+1)); err != nil {
+			log.Fatalf("Failed to set schema version: %v", err)
+		}
+	}
+}
+
+// Schema changes applied in order after the tables are created.
+// The number of applied migrations is stored as the user_version of the database,
+// so new changes must be appended and existing ones never edited.
+var migrations = []string{
+	"ALTER TABLE worlds ADD COLUMN hotbar_selected INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE worlds ADD COLUMN generator TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE blocks ADD COLUMN level INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE worlds ADD COLUMN min_height INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE worlds ADD COLUMN max_height INTEGER NOT NULL DEFAULT 256",
+	"ALTER TABLE worlds ADD COLUMN time INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE worlds ADD COLUMN time_frozen INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE blocks ADD COLUMN facing INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE blocks ADD COLUMN top INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE blocks ADD COLUMN state INTEGER NOT NULL DEFAULT 0",
+	// pack the columns into the state with the bits of levelProperty, facingProperty and topProperty
+	"UPDATE blocks SET state = level | (facing << 3) | (top << 6)",
+	"ALTER TABLE blocks DROP COLUMN level",
+	"ALTER TABLE blocks DROP COLUMN facing",
+	"ALTER TABLE blocks DROP COLUMN top",
 }
 
 type (
@@ -90,6 +140,11 @@ type (
 		name                      string
 		inventory                 string
 		playerX, playerY, playerZ float32
+		hotbarSelected            int
+		generator                 string
+		minHeight, maxHeight      int
+		time                      int64
+		timeFrozen                bool
 	}
 	ChunkEntity struct {
 		id       int
@@ -101,17 +156,24 @@ type (
 		i, j, k   int
 		blockType string
 		active    bool
+		state     BlockState
+	}
+	InventorySlotEntity struct {
+		worldId   int
+		slot      int
+		blockType string
+		count     int
 	}
 )
 
 func (d *Database) World(id int) *WorldEntity {
-	res := d.db.QueryRow("SELECT id, name, inventory, player_x, player_y, player_z FROM worlds WHERE id = ?", id)
+	res := d.db.QueryRow("SELECT id, name, inventory, player_x, player_y, player_z, hotbar_selected, generator, min_height, max_height, time, time_frozen FROM worlds WHERE id = ?", id)
 	if res == nil {
 		return nil
 	}
 
 	var world WorldEntity
-	if err := res.Scan(&world.id, &world.name, &world.inventory, &world.playerX, &world.playerY, &world.playerZ); err != nil {
+	if err := res.Scan(&world.id, &world.name, &world.inventory, &world.playerX, &world.playerY, &world.playerZ, &world.hotbarSelected, &world.generator, &world.minHeight, &world.maxHeight, &world.time, &world.timeFrozen); err != nil {
 		return nil
 	}
 
@@ -119,7 +181,7 @@ func (d *Database) World(id int) *WorldEntity {
 }
 
 func (d *Database) Worlds() []*WorldEntity {
-	res, err := d.db.Query("SELECT id, name, inventory, player_x, player_y, player_z FROM worlds")
+	res, err := d.db.Query("SELECT id, name, inventory, player_x, player_y, player_z, hotbar_selected, generator, min_height, max_height, time, time_frozen FROM worlds")
 	if err != nil {
 		log.Fatal(err)
 		return nil
@@ -130,7 +192,7 @@ func (d *Database) Worlds() []*WorldEntity {
 	out := []*WorldEntity{}
 	for res.Next() {
 		var w WorldEntity
-		if err := res.Scan(&w.id, &w.name, &w.inventory, &w.playerX, &w.playerY, &w.playerZ); err != nil {
+		if err := res.Scan(&w.id, &w.name, &w.inventory, &w.playerX, &w.playerY, &w.playerZ, &w.hotbarSelected, &w.generator, &w.minHeight, &w.maxHeight, &w.time, &w.timeFrozen); err != nil {
 			log.Fatal(err)
 		}
 
@@ -147,12 +209,16 @@ func (d *Database) Worlds() []*WorldEntity {
 
 func (d *Database) CreateWorld(name string) int {
 	r, err := d.db.Exec(
-		"INSERT INTO worlds (name, inventory, player_x, player_y, player_z) VALUES (?, ?, ?, ?, ?)",
+		"INSERT INTO worlds (name, inventory, player_x, player_y, player_z, generator, min_height, max_height, time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		name,
 		"{}",
 		startPosition.X(),
 		startPosition.Y(),
 		startPosition.Z(),
+		strings.Join(defaultGeneratorStages, ","),
+		defaultMinHeight,
+		defaultMaxHeight,
+		worldStartTime,
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -179,6 +245,30 @@ func (d *Database) UpdatePosition(worldId int, x, y, z float32) {
 	}
 }
 
+func (d *Database) UpdateTime(worldId int, ticks int64, frozen bool) {
+	_, err := d.db.Exec(`
+		UPDATE worlds
+		SET time = ?, time_frozen = ?
+		WHERE id = ?
+	`, ticks, frozen, worldId)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+}
+
+func (d *Database) UpdateHotbarSelected(worldId int, slot int) {
+	_, err := d.db.Exec(`
+		UPDATE worlds
+		SET hotbar_selected = ?
+		WHERE id = ?
+	`, slot, worldId)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+}
+
 func (d *Database) UpdateInventory(worldId int, content map[string]int) {
 	jsonString, err := json.Marshal(content)
 	if err != nil {
@@ -197,6 +287,7 @@ func (d *Database) UpdateInventory(worldId int, content map[string]int) {
 	}
 }
 
+// Returns the legacy inventory counts stored before slots were persisted.
 func (w *WorldEntity) Inventory() map[string]int {
 	var invMap map[string]int
 	if err := json.Unmarshal([]byte(w.inventory), &invMap); err != nil {
@@ -205,6 +296,15 @@ func (w *WorldEntity) Inventory() map[string]int {
 	return invMap
 }
 
+// Returns the names of the generator stages of the world in order.
+// Worlds created before the stages were stored use the legacy stages.
+func (w *WorldEntity) GeneratorStages() []string {
+	if w.generator == "" {
+		return legacyGeneratorStages
+	}
+	return strings.Split(w.generator, ",")
+}
+
 func (d *Database) FindChunk(worldId, x, y, z int) *ChunkEntity {
 	res := d.db.QueryRow("SELECT id, world_id, x, y, z FROM chunks WHERE world_id = ? AND x = ? AND y = ? AND z = ?", worldId, x, y, z)
 	if res == nil {
@@ -235,13 +335,13 @@ func (d *Database) CreateChunk(worldId, x, y, z int) int {
 }
 
 func (d *Database) Block(chunkId, i, j, k int) *BlockEntity {
-	res := d.db.QueryRow("SELECT chunk_id, i, j, k, block_type, active FROM blocks WHERE chunk_id = ? AND i = ? AND j = ? AND k = ?", chunkId, i, j, k)
+	res := d.db.QueryRow("SELECT chunk_id, i, j, k, block_type, active, state FROM blocks WHERE chunk_id = ? AND i = ? AND j = ? AND k = ?", chunkId, i, j, k)
 	if res == nil {
 		return nil
 	}
 
 	block := &BlockEntity{}
-	if err := res.Scan(&block.chunkId, &block.i, &block.j, &block.k, &block.blockType, &block.active); err != nil {
+	if err := res.Scan(&block.chunkId, &block.i, &block.j, &block.k, &block.blockType, &block.active, &block.state); err != nil {
 		return nil
 	}
 
@@ -249,7 +349,7 @@ func (d *Database) Block(chunkId, i, j, k int) *BlockEntity {
 }
 
 func (d *Database) Blocks(chunkId int) []*BlockEntity {
-	res, err := d.db.Query("SELECT chunk_id, i, j, k, block_type, active FROM blocks WHERE chunk_id = ?", chunkId)
+	res, err := d.db.Query("SELECT chunk_id, i, j, k, block_type, active, state FROM blocks WHERE chunk_id = ?", chunkId)
 	if err != nil {
 		log.Fatal(err)
 		return nil
@@ -260,7 +360,7 @@ func (d *Database) Blocks(chunkId int) []*BlockEntity {
 	var out []*BlockEntity
 	for res.Next() {
 		var block BlockEntity
-		if err := res.Scan(&block.chunkId, &block.i, &block.j, &block.k, &block.blockType, &block.active); err != nil {
+		if err := res.Scan(&block.chunkId, &block.i, &block.j, &block.k, &block.blockType, &block.active, &block.state); err != nil {
 			log.Fatal(err)
 			return nil
 		}
@@ -276,13 +376,13 @@ func (d *Database) Blocks(chunkId int) []*BlockEntity {
 	return out
 }
 
-func (d *Database) CreateBlock(chunkId, i, j, k int, blockType string, active bool) {
+func (d *Database) CreateBlock(chunkId, i, j, k int, blockType string, active bool, state BlockState) {
 	activeVal := 0
 	if active {
 		activeVal = 1
 	}
 
-	_, err := d.db.Exec("INSERT INTO blocks (chunk_id, i, j, k, block_type, active) VALUES (?, ?, ?, ?, ?, ?)", chunkId, i, j, k, blockType, activeVal)
+	_, err := d.db.Exec("INSERT INTO blocks (chunk_id, i, j, k, block_type, active, state) VALUES (?, ?, ?, ?, ?, ?, ?)", chunkId, i, j, k, blockType, activeVal, state)
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -297,11 +397,67 @@ func (d *Database) UpdateBlock(b *BlockEntity) {
 
 	_, err := d.db.Exec(`
 		UPDATE blocks
-		SET block_type = ?, active = ?
+		SET block_type = ?, active = ?, state = ?
 		WHERE chunk_id = ? AND i = ? AND j = ? AND k = ?
-	`, b.blockType, activeVal, b.chunkId, b.i, b.j, b.k)
+	`, b.blockType, activeVal, b.state, b.chunkId, b.i, b.j, b.k)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+}
+
+func (d *Database) InventorySlots(worldId int) []*InventorySlotEntity {
+	res, err := d.db.Query("SELECT world_id, slot, block_type, count FROM inventory_slots WHERE world_id = ?", worldId)
 	if err != nil {
+		log.Fatal(err)
+		return nil
+	}
+
+	defer res.Close()
+
+	var out []*InventorySlotEntity
+	for res.Next() {
+		var slot InventorySlotEntity
+		if err := res.Scan(&slot.worldId, &slot.slot, &slot.blockType, &slot.count); err != nil {
+			log.Fatal(err)
+			return nil
+		}
+
+		out = append(out, &slot)
+	}
+
+	if err := res.Err(); err != nil {
+		log.Fatal(err)
+		return nil
+	}
+
+	return out
+}
+
+// Replaces all the persisted inventory slots of a world.
+func (d *Database) UpdateInventorySlots(worldId int, slots []*InventorySlotEntity) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM inventory_slots WHERE world_id = ?", worldId); err != nil {
+		tx.Rollback()
 		log.Fatal(err)
 		return
 	}
+
+	for _, s := range slots {
+		_, err := tx.Exec("INSERT INTO inventory_slots (world_id, slot, block_type, count) VALUES (?, ?, ?, ?)", worldId, s.slot, s.blockType, s.count)
+		if err != nil {
+			tx.Rollback()
+			log.Fatal(err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Blocks saved with a fluid level or an orientation before block states keep them.
+func TestMigrateColumnsToState(t *testing.T) {
+	d := newDatabase(filepath.Join(t.TempDir(), "db"))
+
+	// schema from before block states
+	all := migrations
+	defer func() { migrations = all }()
+	stateMigration := slices.Index(all, "ALTER TABLE blocks ADD COLUMN state INTEGER NOT NULL DEFAULT 0")
+	if stateMigration < 0 {
+		t.Fatal("no migration adding the state column")
+	}
+	migrations = all[:stateMigration]
+	d.Migrate()
+
+	worldId := d.CreateWorld("test")
+	chunkId := d.CreateChunk(worldId, 0, 0, 0)
+	insert := "INSERT INTO blocks (chunk_id, i, j, k, block_type, active, level, facing, top) VALUES (?, ?, 0, 0, ?, 1, ?, ?, ?)"
+	if _, err := d.db.Exec(insert, chunkId, 0, "water", 5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.db.Exec(insert, chunkId, 1, "planks-stairs", 0, int(east), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations = all
+	d.Migrate()
+
+	tests := []struct {
+		i        int
+		property *BlockProperty
+		want     int
+	}{
+		{0, levelProperty, 5},
+		{1, facingProperty, int(east)},
+		{1, topProperty, 1},
+	}
+	for _, tt := range tests {
+		b := d.Block(chunkId, tt.i, 0, 0)
+		if b == nil {
+			t.Fatalf("block %d lost by the migration", tt.i)
+		}
+		if got := b.state.Get(tt.property); got != tt.want {
+			t.Fatalf("%s of block %d after migration %d, want %d", tt.property.name, tt.i, got, tt.want)
+		}
+	}
+}
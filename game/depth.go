@@ -1,51 +1,215 @@
 package game
 
 import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
 )
 
+// DepthMap holds the depth of the world seen from the light for shadow mapping.
+// The camera view is split by distance in cascades, each rendered to its own layer of the depth texture,
+// so near shadows are sharp while the far terrain still has shadows.
 type DepthMap struct {
 	fbo, texture uint32
+
+	// width and height of each layer in texels
+	resolution int32
+
+	// cascades from the nearest to the farthest
+	cascades []ShadowCascade
+}
+
+// ShadowCascade is the part of the camera view covered by a layer of the depth map.
+type ShadowCascade struct {
+	// light view projection of the layer
+	mat mgl32.Mat4
+
+	// distance along the camera view where the cascade ends
+	far float32
+
+	// size of a texel in the world
+	texelSize float32
 }
 
 const (
-	depthMapWidth  = 1024
-	depthMapHeight = 1024
+	// default size of the layers and number of cascades
+	shadowMapResolution = 2048
+	shadowCascadeCount  = 4
+
+	// most cascades the chunk shader can sample
+	maxShadowCascades = 4
+
+	// range of the size of the layers, which must be a power of two
+	minShadowMapResolution = 256
+	maxShadowMapResolution = 8192
+
+	// distance along the camera view covered by the cascades
+	shadowDistance = visibleRadius
+
+	// weight of the logarithmic splits against uniform splits, and the distance the logarithmic splits start at
+	shadowSplitLambda = 0.8
+	shadowSplitNear   = 1.0
+
+	// distance towards the light of the blocks casting shadows into a cascade
+	shadowCasterDistance = 150
 )
 
-func newDepthMap() *DepthMap {
-	return &DepthMap{}
+func newDepthMap(resolution int32, cascades int) *DepthMap {
+	if err := checkShadowSettings(resolution, cascades); err != nil {
+		log.Panic(err)
+	}
+
+	return &DepthMap{
+		resolution: resolution,
+		cascades:   make([]ShadowCascade, cascades),
+	}
+}
+
+// Returns an error if the depth map can't have the resolution or number of cascades.
+func checkShadowSettings(resolution int32, cascades int) error {
+	if cascades < 1 || cascades > maxShadowCascades {
+		return fmt.Errorf("invalid shadow cascade count %d, should be 1 to %d", cascades, maxShadowCascades)
+	}
+	if resolution < minShadowMapResolution || resolution > maxShadowMapResolution || resolution&(resolution-1) != 0 {
+		return fmt.Errorf("invalid shadow map resolution %d, should be a power of two from %d to %d", resolution, minShadowMapResolution, maxShadowMapResolution)
+	}
+	return nil
 }
 
 func (d *DepthMap) Init() {
 	gl.GenFramebuffers(1, &d.fbo)
 	gl.GenTextures(1, &d.texture)
-	gl.BindTexture(gl.TEXTURE_2D, d.texture)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT, depthMapWidth, depthMapHeight, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, d.texture)
+	gl.TexImage3D(gl.TEXTURE_2D_ARRAY, 0, gl.DEPTH_COMPONENT32F, d.resolution, d.resolution, int32(len(d.cascades)), 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
 	color := []float32{1, 1, 1, 1}
-	gl.TexParameterfv(gl.TEXTURE_2D, gl.TEXTURE_BORDER_COLOR, &color[0])
+	gl.TexParameterfv(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_BORDER_COLOR, &color[0])
 
 	gl.BindFramebuffer(gl.FRAMEBUFFER, d.fbo)
-	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, d.texture, 0)
+	gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, d.texture, 0, 0)
 	gl.DrawBuffer(gl.NONE)
 	gl.ReadBuffer(gl.NONE)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
 }
 
-func (d *DepthMap) Prepare() {
-	gl.Viewport(0, 0, depthMapWidth, depthMapHeight)
+// Deletes the texture and framebuffer from gpu.
+func (d *DepthMap) Destroy() {
+	gl.DeleteFramebuffers(1, &d.fbo)
+	d.fbo = 0
+	gl.DeleteTextures(1, &d.texture)
+	d.texture = 0
+}
+
+// Recreates the layers of the depth map with another resolution and number of cascades.
+// The cascades are fitted again on the next frame.
+func (d *DepthMap) Resize(resolution int32, cascades int) error {
+	if err := checkShadowSettings(resolution, cascades); err != nil {
+		return err
+	}
+
+	d.Destroy()
+	d.resolution = resolution
+	d.cascades = make([]ShadowCascade, cascades)
+	d.Init()
+	return nil
+}
+
+// Fits the cascades to the camera view as seen from the light.
+// Should be called each frame before the depth of the cascades is rendered.
+func (d *DepthMap) Fit(camera *Camera, light *Light) {
+	view := mgl32.LookAtV(camera.pos, camera.pos.Add(camera.view), camera.up)
+	lightView := mgl32.LookAtV(mgl32.Vec3{}, light.view, mgl32.Vec3{0, 1, 0})
+
+	prev := float32(near)
+	for i := range d.cascades {
+		// blend of logarithmic and uniform splits, logarithmic splits keep the near cascades small
+		t := float64(i+1) / float64(len(d.cascades))
+		logSplit := float32(shadowSplitNear * math.Pow(shadowDistance/shadowSplitNear, t))
+		uniformSplit := lerp(float32(t), near, shadowDistance)
+		split := lerp(shadowSplitLambda, uniformSplit, logSplit)
+
+		// bounding sphere of the part of the view, its size doesn't change as the camera turns
+		inv := mgl32.Perspective(mgl32.DegToRad(fov), aspect, prev, split).Mul4(view).Inv()
+		var corners [8]mgl32.Vec3
+		var center mgl32.Vec3
+		for c := range corners {
+			ndc := mgl32.Vec4{float32(c&1)*2 - 1, float32(c>>1&1)*2 - 1, float32(c>>2&1)*2 - 1, 1}
+			p := inv.Mul4x1(ndc)
+			corners[c] = p.Vec3().Mul(1 / p.W())
+			center = center.Add(corners[c])
+		}
+		center = center.Mul(1.0 / float32(len(corners)))
+
+		var radius float32
+		for _, c := range corners {
+			radius = max(radius, c.Sub(center).Len())
+		}
+		radius = ceil(radius*16) / 16
+
+		// move the center by whole texels in light space so the shadows don't shimmer as the camera moves
+		texel := 2 * radius / float32(d.resolution)
+		lc := lightView.Mul4x1(center.Vec4(1)).Vec3()
+		lc[0] = floor(lc[0]/texel) * texel
+		lc[1] = floor(lc[1]/texel) * texel
+
+		proj := mgl32.Ortho(lc[0]-radius, lc[0]+radius, lc[1]-radius, lc[1]+radius, -lc[2]-radius-shadowCasterDistance, -lc[2]+radius)
+		d.cascades[i] = ShadowCascade{
+			mat:       proj.Mul4(lightView),
+			far:       split,
+			texelSize: texel,
+		}
+		prev = split
+	}
+}
+
+// Prepares the rendering of the depth of a cascade to its layer.
+func (d *DepthMap) Prepare(cascade int) {
+	gl.Viewport(0, 0, d.resolution, d.resolution)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, d.fbo)
+	gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, d.texture, 0, int32(cascade))
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
 	gl.CullFace(gl.FRONT)
 }
 
-func (d *DepthMap) Restore() {
+// Restores the default framebuffer and a viewport of its size.
+func (d *DepthMap) Restore(width, height int32) {
 	gl.CullFace(gl.BACK)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
-	scrWidth, scrHeight := glfw.GetCurrentContext().GetFramebufferSize()
-	gl.Viewport(0, 0, int32(scrWidth), int32(scrHeight))
+	gl.Viewport(0, 0, width, height)
+}
+
+// Returns the console command showing and changing the resolution and number of cascades of the depth map.
+func shadowsCommand(d *DepthMap) *Command {
+	return &Command{
+		usage: fmt.Sprintf("shadows <resolution> <cascades 1-%d>, shadows query", maxShadowCascades),
+		run: func(args []string) (string, error) {
+			if len(args) == 1 && args[0] == "query" {
+				return fmt.Sprintf("shadows %d %d", d.resolution, len(d.cascades)), nil
+			}
+
+			if len(args) != 2 {
+				return "", fmt.Errorf("missing resolution or cascades")
+			}
+			resolution, err := strconv.ParseInt(args[0], 10, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid resolution %q", args[0])
+			}
+			cascades, err := strconv.Atoi(args[1])
+			if err != nil {
+				return "", fmt.Errorf("invalid cascades %q", args[1])
+			}
+
+			if err := d.Resize(int32(resolution), cascades); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("shadows %d %d", d.resolution, len(d.cascades)), nil
+		},
+	}
 }
@@ -0,0 +1,30 @@
+package game
+
+import "testing"
+
+func TestCheckShadowSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolution int32
+		cascades   int
+		valid      bool
+	}{
+		{"default", shadowMapResolution, shadowCascadeCount, true},
+		{"smallest", minShadowMapResolution, 1, true},
+		{"largest", maxShadowMapResolution, maxShadowCascades, true},
+		{"no cascades", shadowMapResolution, 0, false},
+		{"too many cascades", shadowMapResolution, maxShadowCascades + 1, false},
+		{"too small", minShadowMapResolution / 2, 1, false},
+		{"too large", maxShadowMapResolution * 2, 1, false},
+		{"not a power of two", 1000, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkShadowSettings(tt.resolution, tt.cascades)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("checkShadowSettings(%d, %d) = %v, want valid %v", tt.resolution, tt.cascades, err, tt.valid)
+			}
+		})
+	}
+}
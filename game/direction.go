@@ -56,3 +56,46 @@ func (d Direction) Normal() mgl32.Vec3 {
 	new := directions[d]
 	return new
 }
+
+// Returns the direction pointing the other way.
+func (d Direction) Opposite() Direction {
+	if d == noDirection {
+		return noDirection
+	}
+	// directions are listed in opposite pairs
+	return d ^ 1
+}
+
+// Returns the name of the direction.
+func (d Direction) String() string {
+	switch d {
+	case north:
+		return "north"
+	case south:
+		return "south"
+	case down:
+		return "down"
+	case up:
+		return "up"
+	case west:
+		return "west"
+	case east:
+		return "east"
+	}
+	return "none"
+}
+
+// Returns the horizontal direction closest to the vector, ignoring its height.
+func newHorizontalDirection(v mgl32.Vec3) Direction {
+	if abs(v.X()) > abs(v.Z()) {
+		if v.X() < 0 {
+			return west
+		}
+		return east
+	}
+
+	if v.Z() < 0 {
+		return north
+	}
+	return south
+}
@@ -0,0 +1,205 @@
+package game
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Feature is an object of the world like a tree that can extend past the chunk it is seeded in.
+// Features only depend on the seed and the noise, never on generated chunks,
+// so every chunk they cross places the same blocks whatever the generation order.
+type Feature interface {
+	// name of the feature, also salts its random sequence in each chunk
+	Name() string
+
+	// max number of chunks the features extend past the chunk seeding them
+	Reach() int
+
+	// places the blocks of the features seeded in the chunk at pos
+	Place(pos mgl32.Vec3, rng *rand.Rand, place func(FeatureBlock))
+}
+
+// FeatureBlock is one block placed by a feature.
+type FeatureBlock struct {
+	// world position of the block
+	pos [3]int
+
+	blockType string
+
+	// if the block can replace terrain, otherwise only air or the hosts are filled
+	replace bool
+
+	// block types the block replaces instead of air when it doesn't replace terrain
+	hosts []string
+}
+
+// Returns true if the block can be placed over a block of the type.
+func (b FeatureBlock) CanReplace(blockType string) bool {
+	if b.replace {
+		return true
+	}
+	if len(b.hosts) > 0 {
+		return slices.Contains(b.hosts, blockType)
+	}
+	return blockType == ""
+}
+
+// FeaturePlan holds the blocks of the features seeded in one chunk,
+// grouped by the chunk they fall in so each chunk only applies its own blocks.
+type FeaturePlan struct {
+	blocks map[mgl32.Vec3][]FeatureBlock
+}
+
+// Identifies a plan in the generator cache.
+type featurePlanKey struct {
+	feature string
+	x, z    int
+}
+
+// plans are cheap to recompute, the cache is dropped when it grows past this size
+const maxCachedFeaturePlans = 4096
+
+// Returns the plan of a feature for the chunk column at pos, whose y is 0.
+// Plans are computed from the chunk random sequence and cached.
+func (w *WorldGenerator) Plan(feature Feature, pos mgl32.Vec3) *FeaturePlan {
+	key := featurePlanKey{feature.Name(), int(pos.X()), int(pos.Z())}
+	if plan, ok := w.plans[key]; ok {
+		return plan
+	}
+
+	if len(w.plans) >= maxCachedFeaturePlans {
+		clear(w.plans)
+	}
+
+	plan := &FeaturePlan{blocks: map[mgl32.Vec3][]FeatureBlock{}}
+	feature.Place(pos, w.ChunkRand(pos, featureSalt(feature.Name())), func(b FeatureBlock) {
+		target := mgl32.Vec3{
+			float32(floorDiv(b.pos[0], chunkWidth) * chunkWidth),
+			0,
+			float32(floorDiv(b.pos[2], chunkWidth) * chunkWidth),
+		}
+		plan.blocks[target] = append(plan.blocks[target], b)
+	})
+	w.plans[key] = plan
+	return plan
+}
+
+// Returns the salt of the random sequence of a feature.
+func featureSalt(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Places a feature in the terrain.
+// The plans of the chunks around the buffer are applied in a fixed order,
+// keeping only the blocks falling in the buffer.
+type FeatureStage struct {
+	gen *WorldGenerator
+
+	feature Feature
+}
+
+func (s *FeatureStage) Name() string {
+	return s.feature.Name()
+}
+
+func (s *FeatureStage) Generate(buf *ChunkBuffer) {
+	// plans don't depend on the height of the world
+	column := mgl32.Vec3{buf.pos.X(), 0, buf.pos.Z()}
+	reach := s.feature.Reach()
+	for dx := -reach; dx <= reach; dx++ {
+		for dz := -reach; dz <= reach; dz++ {
+			source := column.Add(mgl32.Vec3{float32(dx * chunkWidth), 0, float32(dz * chunkWidth)})
+			for _, b := range s.gen.Plan(s.feature, source).blocks[column] {
+				x, y, z := b.pos[0]-int(buf.pos.X()), b.pos[1]-int(buf.pos.Y()), b.pos[2]-int(buf.pos.Z())
+				if !buf.InBounds(x, y, z) {
+					continue
+				}
+
+				if b.CanReplace(buf.blocks[x][y][z]) {
+					buf.blocks[x][y][z] = b.blockType
+				}
+			}
+		}
+	}
+}
+
+// Grows the trees of the biome of random columns.
+// Trees stand on the terrain height, so they can cross chunk borders.
+type TreeFeature struct {
+	gen *WorldGenerator
+
+	// leaves kept around the trunk, the same for every tree
+	fallout NoiseMap3D
+}
+
+func newTreeFeature(gen *WorldGenerator) *TreeFeature {
+	return &TreeFeature{
+		gen:     gen,
+		fallout: gen.TreeFallout(treeLeavesWidth, treeLeavesHeight, treeLeavesWidth),
+	}
+}
+
+func (f *TreeFeature) Name() string {
+	return "trees"
+}
+
+func (f *TreeFeature) Reach() int {
+	return 1
+}
+
+func (f *TreeFeature) Place(pos mgl32.Vec3, rng *rand.Rand, place func(FeatureBlock)) {
+	for range treeAttempts {
+		// draw every value first so skipped attempts don't shift the next ones
+		x := int(pos.X()) + rng.Intn(chunkWidth)
+		z := int(pos.Z()) + rng.Intn(chunkWidth)
+		chance := rng.Float32()
+		pick := rng.Int()
+
+		biome := f.gen.Biome(float32(x), float32(z)).Biome()
+		if len(biome.trunks) == 0 || chance >= biome.treeDensity {
+			continue
+		}
+
+		// no trees under the sea, over caves or above the world
+		ground := int(math.Floor(float64(f.gen.Height(float32(x), float32(z)))))
+		if f.gen.HasStage("sea") && ground < seaLevel {
+			continue
+		}
+		if f.gen.HasStage("caves") && f.gen.Cave(x, ground, z) {
+			continue
+		}
+		top := ground + treeTrunkHeight - 1
+		if top+treeLeavesHeight >= f.gen.maxHeight {
+			continue
+		}
+
+		// trunk
+		trunk := biome.trunks[pick%len(biome.trunks)]
+		for y := ground + 1; y <= top; y++ {
+			place(FeatureBlock{[3]int{x, y, z}, trunk, true, nil})
+		}
+
+		if biome.leaves == "" {
+			continue
+		}
+
+		// leaves are centered on the trunk
+		corner := [3]int{x - treeLeavesWidth/2, top - 1, z - treeLeavesWidth/2}
+		for i := range treeLeavesWidth {
+			for j := range treeLeavesHeight {
+				for k := range treeLeavesWidth {
+					if f.fallout.At(i, j, k) < treeFallout {
+						continue
+					}
+					place(FeatureBlock{[3]int{corner[0] + i, corner[1] + j, corner[2] + k}, biome.leaves, false, nil})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Features crossing chunk borders must place the same blocks whatever the order the chunks are generated in.
+func TestFeaturesIndependentOfGenerationOrder(t *testing.T) {
+	const seed = 7
+	positions := chunkSquare(1)
+	structures := newStructureLibrary("../structures")
+
+	generate := func(order []mgl32.Vec3) map[mgl32.Vec3]BlockTypes {
+		gen := newWorldGenerator(seed, 0, 256, defaultGeneratorStages, structures)
+		out := map[mgl32.Vec3]BlockTypes{}
+		for _, pos := range order {
+			out[pos] = gen.Terrain(pos)
+		}
+		return out
+	}
+
+	reversed := slices.Clone(positions)
+	slices.Reverse(reversed)
+	first := generate(positions)
+	second := generate(reversed)
+
+	for _, pos := range positions {
+		for x := range chunkWidth {
+			for y := range first[pos][x] {
+				for z := range chunkWidth {
+					if a, b := first[pos][x][y][z], second[pos][x][y][z]; a != b {
+						t.Fatalf("chunk %v has %q at %d,%d,%d in one order and %q in the other", pos, a, x, y, z, b)
+					}
+				}
+			}
+		}
+	}
+
+	// the chunks must have trees crossing their borders for the test to mean anything
+	gen := newWorldGenerator(seed, 0, 256, defaultGeneratorStages, structures)
+	trees := newTreeFeature(gen)
+	crossing := 0
+	for _, pos := range positions {
+		for target, blocks := range gen.Plan(trees, pos).blocks {
+			if target != pos && slices.Contains(positions, target) {
+				crossing += len(blocks)
+			}
+		}
+	}
+	if crossing == 0 {
+		t.Fatal("no tree crosses a border between the chunks")
+	}
+}
@@ -0,0 +1,188 @@
+package game
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Fluid defines how a fluid block type flows and acts on the bodies in it.
+type Fluid struct {
+	// simulation ticks between a change next to the fluid and its update
+	tickDelay int
+
+	// level added to the fluid at each block it flows away from its source
+	levelStep int
+
+	// drawn in the translucent pass instead of with the opaque blocks
+	translucent bool
+
+	// fraction of the gravity cancelled for bodies in the fluid
+	buoyancy float32
+
+	// force slowing down bodies moving in the fluid, relative to their mass and velocity
+	drag float32
+
+	// light levels absorbed by each block of the fluid, on top of the level lost per block
+	lightOpacity int
+}
+
+const (
+	// flowing fluids disappear past this level
+	fluidMaxLevel = 7
+
+	// max number of fluid updates in one tick, the others wait for the next ticks
+	fluidUpdatesPerTick = 64
+)
+
+// Fluids by block type, water flows fast and far while lava is slow and short.
+var blockFluids = map[string]*Fluid{
+	"water": {tickDelay: 5, levelStep: 1, translucent: true, buoyancy: 0.9, drag: 2, lightOpacity: 2},
+	"lava":  {tickDelay: 30, levelStep: 2, buoyancy: 0.8, drag: 4, lightOpacity: maxLightLevel},
+}
+
+// Directions fluids spread in when they can't fall.
+var horizontalDirections = []mgl32.Vec3{
+	directions[north],
+	directions[south],
+	directions[west],
+	directions[east],
+}
+
+// Returns the fluid of the block or nil if the block is not an active fluid.
+func (b *Block) Fluid() *Fluid {
+	if !b.active {
+		return nil
+	}
+	return blockFluids[b.blockType]
+}
+
+// Returns true if the block is active, not a fluid and its shape collides (e.g. not a flower).
+// Only solid blocks collide with bodies.
+func (b *Block) Solid() bool {
+	return b.active && b.Fluid() == nil && b.Shape().collides
+}
+
+// Returns the height of the fluid surface in the block relative to the block size.
+// Sources are almost full and each level lowers the surface.
+func (b *Block) FluidHeight() float32 {
+	return (fluidMaxLevel + 1.5 - float32(b.Level())) / (fluidMaxLevel + 2)
+}
+
+// Schedules the fluids at and around the position to be updated after their tick delay.
+// Should be called when a block changes so the fluids next to it flow in or recede.
+func (w *World) NotifyFluids(pos mgl32.Vec3) {
+	w.notifyFluid(pos)
+	for _, d := range directions {
+		w.notifyFluid(pos.Add(d))
+	}
+}
+
+func (w *World) notifyFluid(pos mgl32.Vec3) {
+	b := w.LoadedBlock(pos)
+	if b == nil {
+		return
+	}
+
+	if fluid := b.Fluid(); fluid != nil {
+		w.ScheduleTick(pos, fluid.tickDelay)
+	}
+}
+
+// Updates the fluid block at the position.
+// Flowing blocks take their level from the fluid feeding them and disappear without it.
+// Fluids fall first and only spread sideways on solid ground.
+func (w *World) updateFluid(pos mgl32.Vec3) {
+	b := w.LoadedBlock(pos)
+	if b == nil {
+		return
+	}
+
+	fluid := b.Fluid()
+	if fluid == nil {
+		return
+	}
+
+	if b.Level() > 0 {
+		level := w.fedLevel(pos, b.blockType, fluid)
+		if level != b.Level() {
+			// the neighbours get notified so the block is updated again with its new level
+			if level > fluidMaxLevel {
+				b.active = false
+				b.state = 0
+			} else {
+				b.SetLevel(level)
+			}
+			w.changeBlock(b)
+			return
+		}
+	}
+
+	// the blocks the fluid may flow into are edited, empty sections are filled with the fluid
+	below := w.EditBlock(pos.Sub(directions[up]))
+	if below == nil {
+		return
+	}
+
+	if !below.Solid() {
+		w.flowInto(below, b.blockType, 1)
+		return
+	}
+
+	level := b.Level() + fluid.levelStep
+	if level > fluidMaxLevel {
+		return
+	}
+
+	for _, d := range horizontalDirections {
+		if n := w.EditBlock(pos.Add(d)); n != nil {
+			w.flowInto(n, b.blockType, level)
+		}
+	}
+}
+
+// Returns the level a flowing block at the position gets from the fluid around it.
+// Returns more than fluidMaxLevel if nothing feeds it.
+func (w *World) fedLevel(pos mgl32.Vec3, blockType string, fluid *Fluid) int {
+	// falling fluid
+	above := w.LoadedBlock(pos.Add(directions[up]))
+	if above != nil && above.Fluid() != nil && above.blockType == blockType {
+		return 1
+	}
+
+	level := fluidMaxLevel + 1
+	for _, d := range horizontalDirections {
+		n := w.LoadedBlock(pos.Add(d))
+		if n == nil || n.Fluid() == nil || n.blockType != blockType {
+			continue
+		}
+
+		// only fluid on solid ground spreads sideways
+		below := w.LoadedBlock(pos.Add(d).Sub(directions[up]))
+		if below == nil || !below.Solid() {
+			continue
+		}
+
+		level = min(level, n.Level()+fluid.levelStep)
+	}
+	return level
+}
+
+// Fills the block with the fluid at the level.
+// Only empty blocks and higher levels of the same fluid are replaced.
+func (w *World) flowInto(b *Block, blockType string, level int) {
+	if b.active && (b.blockType != blockType || b.Level() <= level) {
+		return
+	}
+
+	b.active = true
+	b.SetType(blockType)
+	b.SetLevel(level)
+	w.changeBlock(b)
+}
+
+// Saves a block changed by an update, relights it, rebuffers its section at the end of the tick
+// and notifies the fluids around it.
+func (w *World) changeBlock(b *Block) {
+	w.UpdateLight(b)
+	b.chunk.Invalidate(b.j)
+	w.changedChunks[b.chunk] = true
+	w.SaveBlock(b)
+	w.NotifyFluids(b.WorldPos())
+}
@@ -5,27 +5,65 @@ import (
 )
 
 // Frustrum bounded by 6 planes.
+// The planes face inwards so points inside are at a positive distance from each plane.
 type Frustrum struct {
 	top, bottom, right, left, far, near *Plane
 }
 
+// Extracts the frustrum of a view projection matrix.
+// Each plane is a combination of the rows of the matrix, the clip space
+// conditions -w <= x,y,z <= w written for the world coordinates.
+func newFrustrum(m mgl32.Mat4) *Frustrum {
+	plane := func(sign float32, row int) *Plane {
+		v := m.Row(3).Add(m.Row(row).Mul(sign))
+		normal := v.Vec3()
+		l := normal.Len()
+
+		// the point of the plane closest to the origin
+		return newPlane(normal, normal.Mul(-v.W()/(l*l)))
+	}
+
+	return &Frustrum{
+		left:   plane(1, 0),
+		right:  plane(-1, 0),
+		bottom: plane(1, 1),
+		top:    plane(-1, 1),
+		near:   plane(1, 2),
+		far:    plane(-1, 2),
+	}
+}
+
+// Returns the planes of the frustrum.
+func (f *Frustrum) planes() []*Plane {
+	return []*Plane{f.top, f.bottom, f.right, f.left, f.far, f.near}
+}
+
 // Returns true if the provided point is in the Frustrum.
 func (f *Frustrum) Contains(p mgl32.Vec3) bool {
-	return f.top.Distance(p) >= 0 &&
-		f.bottom.Distance(p) >= 0 &&
-		f.right.Distance(p) >= 0 &&
-		f.left.Distance(p) >= 0 &&
-		f.near.Distance(p) >= 0 &&
-		f.far.Distance(p) >= 0
+	for _, plane := range f.planes() {
+		if plane.Distance(p) < 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // Returns true if the provided box overlaps the Frustrum.
+// The box is outside only if its corner farthest along the normal of a plane is behind it,
+// so boxes larger than the frustrum and crossing it entirely are kept.
+// Boxes near the edges of the frustrum can be kept while just outside of it.
 func (f *Frustrum) Intersects(box Box) bool {
-	c := box.Corners()
-	for _, corner := range c {
-		if f.Contains(corner) {
-			return true
+	for _, plane := range f.planes() {
+		corner := box.min
+		for i := range 3 {
+			if plane.normal[i] >= 0 {
+				corner[i] = box.max[i]
+			}
+		}
+
+		if plane.Distance(corner) < 0 {
+			return false
 		}
 	}
-	return false
+	return true
 }
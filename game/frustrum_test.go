@@ -0,0 +1,62 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestFrustrumIntersects(t *testing.T) {
+	// camera at the origin looking north
+	frustrum := newCamera(mgl32.Vec3{}).Frustrum()
+
+	tests := []struct {
+		name     string
+		min, max mgl32.Vec3
+		want     bool
+	}{
+		{"inside", mgl32.Vec3{-1, -1, -11}, mgl32.Vec3{1, 1, -9}, true},
+		{"around the camera", mgl32.Vec3{-1, -1, -1}, mgl32.Vec3{1, 1, 1}, true},
+		{"behind", mgl32.Vec3{-1, -1, 9}, mgl32.Vec3{1, 1, 11}, false},
+		{"left", mgl32.Vec3{-101, -1, -11}, mgl32.Vec3{-99, 1, -9}, false},
+		{"above", mgl32.Vec3{-1, 99, -11}, mgl32.Vec3{1, 101, -9}, false},
+		{"past the far plane", mgl32.Vec3{-1, -1, -far - 11}, mgl32.Vec3{1, 1, -far - 9}, false},
+		{"straddling the right plane", mgl32.Vec3{0, -1, -20}, mgl32.Vec3{100, 1, -18}, true},
+		{"straddling the far plane", mgl32.Vec3{-1, -1, -far - 10}, mgl32.Vec3{1, 1, -far + 10}, true},
+		{"larger than the frustrum", mgl32.Vec3{-5000, -5000, -5000}, mgl32.Vec3{5000, 5000, 5000}, true},
+		{"wall across the view", mgl32.Vec3{-500, -500, -21}, mgl32.Vec3{500, 500, -20}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frustrum.Intersects(newBox(tt.min, tt.max)); got != tt.want {
+				t.Fatalf("Intersects(%v, %v) = %v, want %v", tt.min, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrustrumContains(t *testing.T) {
+	frustrum := newCamera(mgl32.Vec3{}).Frustrum()
+
+	tests := []struct {
+		name string
+		p    mgl32.Vec3
+		want bool
+	}{
+		{"ahead", mgl32.Vec3{0, 0, -10}, true},
+		{"behind", mgl32.Vec3{0, 0, 10}, false},
+		{"before the near plane", mgl32.Vec3{0, 0, -near / 2}, false},
+		{"past the far plane", mgl32.Vec3{0, 0, -far - 1}, false},
+		{"right", mgl32.Vec3{100, 0, -10}, false},
+		{"below", mgl32.Vec3{0, -100, -10}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frustrum.Contains(tt.p); got != tt.want {
+				t.Fatalf("Contains(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package game
 
 import (
+	"cmp"
 	"log"
+	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
+	"slices"
 	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
@@ -19,6 +22,9 @@ type Game struct {
 	// provides time delta for game loop
 	clock *Clock
 
+	// crafting grid shown in the inventory screen
+	crafting *CraftingGrid
+
 	// crosshair shows a cross on the screen
 	crosshair *Crosshair
 
@@ -28,27 +34,54 @@ type Game struct {
 	// depth from light perspective for shadow lighting
 	depthMap *DepthMap
 
+	// shows fps, position and world details
+	debugOverlay *DebugOverlay
+
 	// hotbar displays inventory bar
 	hotbar *Hotbar
 
+	// renders the world offscreen and applies the post effects
+	post *PostProcessor
+
+	// inventory screen displays all slots and moves stacks with the mouse
+	inventoryScreen *InventoryScreen
+
+	// reads commands typed by the player
+	console *Console
+
 	// last time world details was saved (not blocks as they are currently greedily saved)
 	lastSaved time.Time
 
 	// light source
 	light *Light
 
+	// sky dome behind the world
+	sky *Sky
+
 	// physics engine for player movements and collisions
 	physics *PhysicsEngine
 
+	// crafting recipes loaded from files
+	recipes *RecipeBook
+
+	// structure templates loaded from files
+	structures *StructureLibrary
+
 	// main player
 	player *Player
 
+	// if the frame should be saved as a screenshot once drawn
+	screenshotRequested bool
+
 	// manages shader programs
 	shaders *ShaderManager
 
 	// block the player is currently looking at
 	target *TargetBlock
 
+	// draws text on the screen
+	text *TextRenderer
+
 	// to display textures on a quad on screen corner
 	textureDebug *TextureDebugger
 
@@ -62,6 +95,9 @@ type Game struct {
 	world *World
 
 	pearls map[*Pearl]bool
+
+	// dropped items lying in the world
+	items map[*Item]bool
 }
 
 const (
@@ -89,15 +125,21 @@ func (g *Game) Init() {
 
 	worldEntity := newMenu(g.db).Run()
 
-	g.window = newWindow()
+	g.window = newWindow(true)
+	g.Load(worldEntity)
+}
 
+// Loads the resources and the world in the window.
+func (g *Game) Load(worldEntity *WorldEntity) {
 	gl.Enable(gl.DEPTH_TEST)
 
 	g.shaders = newShaderManager("./shaders")
 	g.textures = newTextureManager("./assets")
 	g.atlas = newTextureAtlas(g.textures.CreateTexture("atlas.png"))
+	g.recipes = newRecipeBook("./recipes")
+	g.structures = newStructureLibrary("./structures")
 
-	g.world = newWorld(g.shaders.Program("chunk"), g.shaders.Program("depth"), g.atlas, worldEntity.id, g.db)
+	g.world = newWorld(g.shaders.Program("chunk"), g.shaders.Program("depth"), g.atlas, worldEntity.id, worldEntity.minHeight, worldEntity.maxHeight, newWorldTime(worldEntity.time, worldEntity.timeFrozen), worldEntity.GeneratorStages(), g.structures, g.db)
 	g.world.Init()
 	g.clock = newClock()
 
@@ -106,51 +148,90 @@ func (g *Game) Init() {
 	log.Println("Spawning at", startPos)
 	g.player = newPlayer(startPos)
 	g.physics = newPhysicsEngine(func(v mgl32.Vec3) Box {
-		return g.world.Block(v).Box()
+		// outside the world height the cell is air
+		if b := g.world.Block(v); b != nil {
+			return b.Box()
+		}
+		return cellBox(v)
 	}, g.world.SurroundingBoxes,
-		func(v mgl32.Vec3) *Box {
+		func(v mgl32.Vec3) []Box {
 			b := g.world.Block(v)
-			if !b.active {
+			if b == nil || !b.Solid() {
 				return nil
 			}
-
-			box := b.Box()
-			return &box
+			return b.Boxes()
+		},
+		func(v mgl32.Vec3) *Fluid {
+			b := g.world.LoadedBlock(v)
+			if b == nil {
+				return nil
+			}
+			return b.Fluid()
 		},
 	)
 	g.physics.Register(g.player.body)
-	g.player.inventory.Set(worldEntity.Inventory())
+	g.LoadInventory(worldEntity)
 
-	g.light = newLight(mgl32.Vec3{})
-	g.light.SetLevel(1.0)
+	g.light = newLight()
+	g.light.Move(g.player.camera.pos, g.world.time)
 
-	// day and night (uncomment to togggle along with `HandleChange()` in the game loop)
-	// g.light.StartDay(time.Second * 10)
+	// captures render without a window and take no input
+	if g.window != nil {
+		g.SetLookHandler()
+		g.SetMouseClickHandler()
+		g.SetHotbarHandler()
+		g.SetKeyHandler()
+	}
 
-	g.SetLookHandler()
-	g.SetMouseClickHandler()
+	g.sky = newSky(g.shaders.Program("sky"))
+	g.sky.Init()
 
 	g.crosshair = newCrosshair(g.shaders.Program("crosshair"))
 	g.crosshair.Init()
 
-	g.hotbar = newHotbar(g.shaders.Program("hotbar"), g.atlas, g.player.camera)
-	g.hotbar.AddAll(worldEntity.Inventory())
+	g.hotbar = newHotbar(g.shaders.Program("hotbar"), g.atlas, g.player.camera, g.player.inventory)
+	g.hotbar.Select(worldEntity.hotbarSelected)
 	g.hotbar.Init()
 
+	g.crafting = newCraftingGrid(g.recipes)
+	g.inventoryScreen = newInventoryScreen(g.shaders.Program("hotbar"), g.atlas, g.player.camera, g.player.inventory, g.crafting, g.ThrowStack)
+	g.inventoryScreen.Init()
+
+	g.text = newTextRenderer(g.shaders.Program("text"), g.atlas)
+	g.text.Init()
+	g.debugOverlay = newDebugOverlay(g.text)
+	g.console = newConsole(g.text)
+	g.console.Register("time", timeCommand(g.world.time))
+
 	// texture debugger on top right of screen (UNCOMMENT TO TOGGLE, along with draw call in game loop)
 	g.textureDebug = newTextureDebugger(g.shaders.Program("debug"))
 	g.textureDebug.Init()
 
-	g.depthMap = newDepthMap()
+	g.depthMap = newDepthMap(shadowMapResolution, shadowCascadeCount)
 	g.depthMap.Init()
+	g.console.Register("shadows", shadowsCommand(g.depthMap))
+
+	g.post = newPostProcessor(g.shaders.Program("post"), g.shaders.Program("blur"), g.shaders.Program("fxaa"))
+	g.post.Init(g.FramebufferSize())
+	g.console.Register("post", postCommand(g.post))
 
 	g.pearls = make(map[*Pearl]bool)
+	g.items = make(map[*Item]bool)
+}
+
+// Returns the size of the framebuffer of the window in pixels, the size of a window when there is none.
+func (g *Game) FramebufferSize() (int32, int32) {
+	if g.window == nil {
+		return windowWidth, windowHeight
+	}
+	w, h := g.window.GetFramebufferSize()
+	return int32(w), int32(h)
 }
 
 // Runs the game loop.
 func (g *Game) Run() {
 	defer g.window.Terminate()
-	g.world.SpawnSurroundings(g.player.body.position)
+	g.world.SpawnSurroundings(g.player.body.position, nil)
 	g.world.DrainSpawnQueue()
 
 	go func() {
@@ -158,93 +239,154 @@ func (g *Game) Run() {
 	}()
 
 	g.clock.Start()
-	for !g.window.ShouldClose() && !g.window.IsPressed(glfw.KeyQ) {
+	for !g.window.ShouldClose() && !(g.window.IsPressed(glfw.KeyQ) && !g.console.open) {
 		g.clock.Tick()
 
 		// simulation loop - get input and simulate world but dont render
 		for g.clock.ShouldSimulate() {
-			// movement
+			// movement, the keys type in the console while it is open
 			g.HandleMove()
-			g.HandleJump()
-			g.HandleThrowPearl()
-			g.HanldleFly()
+			if !g.console.open {
+				g.HandleJump()
+				g.HandleThrowPearl()
+				g.HanldleFly()
+			}
 
 			// interactions
 			g.LookBlock()
-			g.HandleInventorySelect()
+			if !g.console.open {
+				g.HandleInventoryScreen()
+				g.HandleDropItem()
+				g.HandleDebugOverlay()
+			}
 
 			// world
-			g.world.SpawnSurroundings(g.player.body.position)
+			g.world.SpawnSurroundings(g.player.body.position, g.player.camera.Frustrum())
 			g.world.ProcessSpawnQueue()
-
-			// day/night (UNCOMMENT TO TOGGLE)
-			// g.light.HandleChange()
+			g.world.Tick()
 
 			// tick physics simulation
 			g.physics.Tick(g.clock.SimulationDelta())
 
-			lightPos := g.player.camera.pos.Sub(mgl32.Vec3{1, 0, 1}.Normalize().Mul(visibleRadius))
-			lightPos[1] = 200
-			g.light.pos = lightPos
-			g.light.view = g.player.camera.pos.Sub(lightPos).Normalize()
+			// pickup, merge and despawn dropped items
+			g.UpdateItems()
+
+			// sun or moon at the time of day
+			g.light.Move(g.player.camera.pos, g.world.time)
 
 			// consume fix timestep
 			g.clock.ConsumeStep()
 		}
 
-		// get nearby chunks, despawn far chunk and cull non visible chunks
-		near := g.world.CollectChunks(g.player.body.position, func(c *Chunk) bool {
-			return !g.player.Sees(c)
-		})
-
-		// depth pass - render depth to a texture for shadow mapping
-		g.depthMap.Prepare()
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-		for _, c := range near {
-			c.DrawDepthMap(g.light)
-		}
-		g.depthMap.Restore()
+		// world through the post effects, the overlays are drawn over it
+		visible := g.DrawWorld()
 
-		// rendering
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 		g.crosshair.Draw()
 		g.hotbar.Draw()
-
-		for p := range g.pearls {
-			p.Draw(g.player.camera)
+		g.inventoryScreen.Draw()
+
+		// text is batched by the overlays then drawn at once
+		g.debugOverlay.Frame()
+		g.debugOverlay.Add(g.player, g.world, visible)
+		g.console.Add()
+		g.text.Draw()
+
+		// the frame is read before it is swapped
+		if g.screenshotRequested {
+			g.TakeScreenshot()
+			g.screenshotRequested = false
 		}
 
-		// show depth map as seen from the light perspective at top right of screen (UNCOMMENT TO TOGGLE)
-		// g.textureDebug.Draw(g.depthMap.texture)
+		// position and time persistence
+		g.SaveDetails()
+
+		// window maintenance
+		g.window.SwapBuffers()
+		glfw.PollEvents()
+	}
+}
 
-		for _, c := range near {
-			// if a block is being looked at in this chunk
-			var target *TargetBlock
-			if g.target != nil && g.target.block.chunk == c {
-				target = g.target
+// Draws the world seen by the player through the post effects to the output of the post processor.
+// Despawns the far chunks and returns the number of chunks in view.
+func (g *Game) DrawWorld() int {
+	// get nearby chunks, despawn far chunk
+	loaded := g.world.CollectChunks(g.player.body.position, nil)
+
+	// depth pass - render depth of each shadow cascade to a layer of the depth map for shadow mapping
+	// chunks out of view still cast shadows, only the ones out of the cascade frustrum are culled
+	g.depthMap.Fit(g.player.camera, g.light)
+	for i, cascade := range g.depthMap.cascades {
+		g.depthMap.Prepare(i)
+		cascadeFrustrum := newFrustrum(cascade.mat)
+		for _, c := range loaded {
+			if cascadeFrustrum.Intersects(c.Box()) {
+				c.DrawDepthMap(cascade.mat)
 			}
+		}
+	}
+	g.depthMap.Restore(g.post.width, g.post.height)
+
+	// cull the chunks out of view and the sections hidden behind the terrain
+	view := g.player.camera.Frustrum()
+	near := make([]*Chunk, 0, len(loaded))
+	for _, c := range loaded {
+		if view.Intersects(c.Box()) {
+			near = append(near, c)
+		}
+	}
+	near = g.world.MarkVisibleSections(near, g.player.camera)
+
+	// rendering - the world is drawn offscreen for the post effects
+	g.post.Prepare()
+	g.sky.Draw(g.player.camera, g.light)
 
-			c.Draw(target, g.player.camera, g.light, g.depthMap)
+	for p := range g.pearls {
+		p.Draw(g.player.camera)
+	}
+
+	for it := range g.items {
+		it.Draw(g.player.camera)
+	}
+
+	for _, c := range near {
+		// if a block is being looked at in this chunk
+		var target *TargetBlock
+		if g.target != nil && g.target.block.chunk == c {
+			target = g.target
 		}
 
-		// position persistence
-		g.SavePosition()
+		c.Draw(target, g.player.camera, g.light, g.depthMap)
+	}
 
-		// window maintenance
-		g.window.SwapBuffers()
-		glfw.PollEvents()
+	// translucent blocks are blended over the terrain from the farthest chunk to the nearest
+	slices.SortFunc(near, func(a, b *Chunk) int {
+		return cmp.Compare(b.Box().Distance(g.player.camera.pos), a.Box().Distance(g.player.camera.pos))
+	})
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.DepthMask(false)
+	for _, c := range near {
+		c.DrawTranslucent(g.player.camera, g.light, g.depthMap)
 	}
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+
+	// post effects draw the world to the output of the post processor
+	eye := g.world.LoadedBlock(g.player.camera.pos)
+	g.post.underwater = eye != nil && eye.Fluid() == blockFluids["water"]
+	g.post.Resolve()
+	return len(near)
 }
 
 // Looks for blocks from the perspective of player.
 // Will set the target block if currently looking at one.
 func (g *Game) LookBlock() {
 	ray := g.player.Ray()
-	march := ray.March(func(p mgl32.Vec3) *Box {
+	march := ray.March(func(p mgl32.Vec3) []Box {
+		// blocks that don't collide (e.g. flowers) can still be targeted
 		block := g.world.Block(p)
-		if block != nil && block.active {
-			box := block.Box()
-			return &box
+		if block != nil && block.active && block.Fluid() == nil {
+			return block.Boxes()
 		}
 		return nil
 	})
@@ -252,8 +394,9 @@ func (g *Game) LookBlock() {
 	if march.hit {
 		block := g.world.Block(march.blockPos)
 		g.target = &TargetBlock{
-			block: block,
-			face:  march.face,
+			block:  block,
+			face:   march.face,
+			hitPos: march.hitPos,
 		}
 	} else {
 		g.target = nil
@@ -267,7 +410,7 @@ func (g *Game) PlaceBlock() {
 
 	pos := g.target.block.WorldPos()
 	newPos := pos.Add(g.target.face.Normal())
-	block := g.world.Block(newPos)
+	block := g.world.EditBlock(newPos)
 	if block == nil {
 		return
 	}
@@ -282,23 +425,23 @@ func (g *Game) PlaceBlock() {
 		return
 	}
 
-	blockType := g.hotbar.Selected()
-	hasInventory := g.player.inventory.Grab(blockType, 1)
+	slot := g.hotbar.Selected()
+	blockType := g.player.inventory.Slot(slot).blockType
+	hasInventory := g.player.inventory.GrabSlot(slot, 1)
 	if !hasInventory {
 		return
 	}
 
-	// sync with hotbar
 	c := g.player.inventory.Count(blockType)
-	if c == 0 {
-		g.hotbar.Remove(blockType)
-	}
-
 	log.Printf("Placing %s (%d left) at position: %v", blockType, c, block.WorldPos())
 	block.active = true
-	block.blockType = blockType
+	block.SetType(blockType)
+	block.state = placementState(blockType, g.target, g.player.camera.view)
+	g.world.UpdateLight(block)
+	block.chunk.Invalidate(block.j)
 	block.chunk.Buffer()
 	g.world.SaveBlock(block)
+	g.world.NotifyFluids(block.WorldPos())
 	g.SaveInventory()
 }
 
@@ -310,21 +453,121 @@ func (g *Game) BreakBlock() {
 	log.Println("Breaking: ", g.target.block.WorldPos())
 	g.target.block.active = false
 
+	// drop the broken block as an item with a small pop upwards
 	blockType := g.target.block.blockType
-	log.Println("Adding ", blockType, " to inventory")
-	g.player.inventory.Add(blockType, 1)
-	g.hotbar.Add(blockType)
+	velocity := mgl32.Vec3{rand.Float32() - 0.5, 3, rand.Float32() - 0.5}
+	g.SpawnItem(ItemStack{blockType, 1}, g.target.block.WorldPos(), velocity, itemPickupDelay)
+	g.world.UpdateLight(g.target.block)
+	g.target.block.chunk.Invalidate(g.target.block.j)
 	g.target.block.chunk.Buffer()
 
 	g.world.SaveBlock(g.target.block)
+	g.world.NotifyFluids(g.target.block.WorldPos())
+}
+
+// Spawns a dropped item in the world.
+func (g *Game) SpawnItem(stack ItemStack, pos, velocity mgl32.Vec3, pickupDelay time.Duration) {
+	item := newItem(g.atlas, g.shaders.Program("pearl"), stack, pos, velocity, pickupDelay)
+	item.Init()
+	g.physics.Register(item.body)
+	g.items[item] = true
+}
+
+// Despawns a dropped item and destroys the data on gpu.
+func (g *Game) DespawnItem(item *Item) {
+	g.physics.Unregister(item.body)
+	item.Destroy()
+	delete(g.items, item)
+}
+
+// Picks up items near the player, merges items close to each other and despawns expired ones.
+func (g *Game) UpdateItems() {
+	feet := g.player.body.position.Sub(mgl32.Vec3{0, playerHeight / 2, 0})
+	picked := false
+	for item := range g.items {
+		if item.Expired() {
+			g.DespawnItem(item)
+			continue
+		}
+
+		// the inventory doesn't change under the screen, the held stack may go back to its slot
+		if !g.inventoryScreen.open && item.CanPickup() && item.Center().Sub(feet).Len() <= itemPickupRadius {
+			left := g.player.inventory.Add(item.stack.blockType, item.stack.count)
+			if left != item.stack.count {
+				log.Println("Picked up", item.stack.count-left, item.stack.blockType)
+				picked = true
+			}
+
+			item.stack.count = left
+			if item.stack.Empty() {
+				g.DespawnItem(item)
+				continue
+			}
+		}
+
+		for other := range g.items {
+			if other == item || !item.CanMerge(other) || other.Center().Sub(item.Center()).Len() > itemMergeRadius {
+				continue
+			}
+
+			if item.Merge(other) {
+				g.DespawnItem(other)
+			}
+		}
+	}
+
+	if picked {
+		if g.inventoryScreen.open {
+			g.inventoryScreen.Buffer()
+		}
+		g.SaveInventory()
+	}
+}
+
+// Drops one of the selected hotbar blocks in the direction of the view.
+func (g *Game) HandleDropItem() {
+	if !g.window.Debounce(glfw.KeyX) || g.inventoryScreen.open {
+		return
+	}
+
+	slot := g.hotbar.Selected()
+	blockType := g.player.inventory.Slot(slot).blockType
+	if !g.player.inventory.GrabSlot(slot, 1) {
+		return
+	}
+
+	g.ThrowStack(ItemStack{blockType, 1})
 	g.SaveInventory()
 }
 
+// Throws a stack as an item in the direction of the view.
+func (g *Game) ThrowStack(stack ItemStack) {
+	direction := g.player.camera.view.Normalize()
+	pos := g.player.camera.pos.Add(direction)
+	g.SpawnItem(stack, pos, direction.Mul(itemThrowSpeed), itemThrowDelay)
+}
+
 // Sets handlers for mouse click and calls break/place block.
 func (g *Game) SetMouseClickHandler() {
 	var isPressedLeft bool
 	var isPressedRight bool
 	g.window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		// drag and drop stacks while the inventory is open
+		if g.console.open {
+			return
+		}
+
+		if g.inventoryScreen.open {
+			switch {
+			case action == glfw.Press:
+				g.inventoryScreen.PickUp(button == glfw.MouseButtonRight)
+			case action == glfw.Release:
+				g.inventoryScreen.Drop()
+				g.SaveInventory()
+			}
+			return
+		}
+
 		switch button {
 		case glfw.MouseButtonLeft:
 			if action == glfw.Press && !isPressedLeft {
@@ -344,51 +587,141 @@ func (g *Game) SetMouseClickHandler() {
 	})
 }
 
-// Hanldes selection of block in hotbar.
-func (g *Game) HandleInventorySelect() {
-	key := -1
-	switch {
-	case g.window.IsPressed(glfw.Key1):
-		key = 1
-	case g.window.IsPressed(glfw.Key2):
-		key = 2
-	case g.window.IsPressed(glfw.Key3):
-		key = 3
-	case g.window.IsPressed(glfw.Key4):
-		key = 4
-	case g.window.IsPressed(glfw.Key5):
-		key = 5
-	case g.window.IsPressed(glfw.Key6):
-		key = 6
-	case g.window.IsPressed(glfw.Key7):
-		key = 7
-	case g.window.IsPressed(glfw.Key8):
-		key = 8
-	case g.window.IsPressed(glfw.Key9):
-		key = 9
-	}
-
-	key--
-	if key > -1 {
-		g.hotbar.Select(key)
+// Sets handlers for the scroll wheel to select the hotbar slot.
+// Scrolling up moves the selection left like in the original game.
+func (g *Game) SetHotbarHandler() {
+	g.window.SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		switch {
+		case yoff > 0:
+			g.SelectHotbar(func() { g.hotbar.Scroll(-1) })
+		case yoff < 0:
+			g.SelectHotbar(func() { g.hotbar.Scroll(1) })
+		}
+	})
+}
+
+// Sets handlers for typed keys.
+// The console reads the keys while it is open, otherwise slash opens it and number keys select the hotbar slot.
+func (g *Game) SetKeyHandler() {
+	g.window.SetCharCallback(func(w *glfw.Window, char rune) {
+		g.console.Type(char)
+	})
+
+	g.window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if g.console.open {
+			g.console.Key(key, action)
+			return
+		}
+
+		if action != glfw.Press {
+			return
+		}
+
+		switch {
+		case key == glfw.KeySlash && !g.inventoryScreen.open:
+			g.console.Open()
+		case key == glfw.KeyF2:
+			g.screenshotRequested = true
+		case key >= glfw.Key1 && key <= glfw.Key9:
+			g.SelectHotbar(func() { g.hotbar.Select(int(key - glfw.Key1)) })
+		}
+	})
+}
+
+// Applies a change to the hotbar selection and saves it if it moved.
+func (g *Game) SelectHotbar(change func()) {
+	prev := g.hotbar.Selected()
+	change()
+	if g.hotbar.Selected() != prev {
+		g.db.UpdateHotbarSelected(g.world.id, g.hotbar.Selected())
+	}
+}
+
+// Opens and closes the inventory screen.
+// The cursor is released while the screen is open.
+func (g *Game) HandleInventoryScreen() {
+	if !g.window.Debounce(glfw.KeyE) {
+		return
+	}
+
+	if g.inventoryScreen.Toggle() {
+		g.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+		x, y := g.window.GetCursorPos()
+		g.inventoryScreen.MoveCursor(float32(x), float32(y))
+	} else {
+		g.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+		g.SaveInventory()
+
+		// avoid a jump in the view from the cursor moving while released
+		x, y := g.window.GetCursorPos()
+		g.player.camera.prevScreenX, g.player.camera.prevScreenY = float32(x), float32(y)
+	}
+}
+
+// Loads the players inventory from db.
+// Converts the inventory counts of worlds saved before slots existed.
+func (g *Game) LoadInventory(world *WorldEntity) {
+	entities := g.db.InventorySlots(world.id)
+	if len(entities) == 0 {
+		legacy := world.Inventory()
+		if len(legacy) == 0 {
+			return
+		}
+
+		for blockType, count := range legacy {
+			g.player.inventory.Add(blockType, count)
+		}
+		g.db.UpdateInventory(world.id, map[string]int{})
+		g.SaveInventory()
+		return
+	}
+
+	var slots [inventorySize]ItemStack
+	for _, e := range entities {
+		if e.slot >= 0 && e.slot < inventorySize {
+			slots[e.slot] = ItemStack{e.blockType, e.count}
+		}
 	}
+	g.player.inventory.Set(slots)
 }
 
 // Saves the players inventory to db.
 func (g *Game) SaveInventory() {
-	g.db.UpdateInventory(g.world.id, g.player.inventory.content)
+	entities := []*InventorySlotEntity{}
+	for i := range inventorySize {
+		stack := g.player.inventory.Slot(i)
+		if stack.Empty() {
+			continue
+		}
+
+		entities = append(entities, &InventorySlotEntity{
+			worldId:   g.world.id,
+			slot:      i,
+			blockType: stack.blockType,
+			count:     stack.count,
+		})
+	}
+	g.db.UpdateInventorySlots(g.world.id, entities)
 }
 
-// Saves world player position.
-func (g *Game) SavePosition() {
+// Saves world player position and time.
+func (g *Game) SaveDetails() {
 	if time.Since(g.lastSaved) >= worldSaveInterval {
 		pos := g.player.camera.pos
 		log.Println("Saving player position", pos)
 		g.db.UpdatePosition(g.world.id, pos.X(), pos.Y(), pos.Z())
+		g.db.UpdateTime(g.world.id, g.world.time.ticks, g.world.time.frozen)
 		g.lastSaved = time.Now()
 	}
 }
 
+// Toggles the debug overlay.
+func (g *Game) HandleDebugOverlay() {
+	if g.window.Debounce(glfw.KeyF3) {
+		g.debugOverlay.Toggle()
+	}
+}
+
 // Handles flying movement by player.
 func (g *Game) HanldleFly() {
 	if g.window.Debounce(glfw.KeyF) {
@@ -398,6 +731,11 @@ func (g *Game) HanldleFly() {
 
 // Handles jump from pressed keys.
 func (g *Game) HandleJump() {
+	if g.player.body.fluid != nil && g.window.IsPressed(glfw.KeySpace) {
+		g.player.body.Swim()
+		return
+	}
+
 	if g.window.Debounce(glfw.KeySpace) && g.player.body.grounded {
 		g.player.body.Jump()
 	}
@@ -414,6 +752,12 @@ func (g *Game) HandleThrowPearl() {
 }
 
 func (g *Game) HandleMove() {
+	// no movement while a screen has the cursor or the console the keys
+	if g.inventoryScreen.open || g.console.open {
+		g.player.Move(0, 0, false)
+		return
+	}
+
 	// get input for movement
 	var rightMove float32
 	var forwardMove float32
@@ -442,6 +786,10 @@ func (g *Game) HandleMove() {
 // Sets a key callback function to handle mouse movement.
 func (g *Game) SetLookHandler() {
 	g.window.SetCursorPosCallback(func(w *glfw.Window, xpos, ypos float64) {
+		if g.inventoryScreen.open {
+			g.inventoryScreen.MoveCursor(float32(xpos), float32(ypos))
+			return
+		}
 		g.player.camera.Look(float32(xpos), float32(ypos))
 	})
 }
@@ -1,187 +1,246 @@
 package game
 
 import (
+	"log"
 	"math"
+	"math/rand"
 
 	"github.com/go-gl/mathgl/mgl32"
 )
 
 // Generates world terrain and content.
+// Chunks are generated by running a pipeline of stages over a chunk buffer.
 type WorldGenerator struct {
+	// seed of the world, noise and random features derive from it
+	seed int64
+
 	// generates noise map for terrain generation
 	noise *NoiseMapGenerator
+
+	// stages run in order on every chunk
+	stages []GeneratorStage
+
+	// templates placed by the structures stage, can be nil
+	structures *StructureLibrary
+
+	// range of world heights of the generated chunks
+	minHeight, maxHeight int
+
+	// feature plans of the chunks generated recently
+	plans map[featurePlanKey]*FeaturePlan
+
+	// biomes and heights of the chunk columns sampled recently, by chunk coordinates
+	columns map[[2]int]*ColumnNoise
+}
+
+// ColumnNoise holds the noise derived values of the columns of one chunk.
+// Neighbouring chunks and features sample the same columns many times,
+// so the values are computed once and cached by the generator.
+type ColumnNoise struct {
+	biomes [chunkWidth][chunkWidth]BiomeID
+
+	// terrain heights, computed on first use since they need the biomes around the chunk
+	heights *[chunkWidth][chunkWidth]float32
 }
 
-// Defines the block types for one chunk.
-type BlockTypes [chunkWidth][chunkHeight][chunkWidth]string
+// columns are cheap to recompute, the cache is dropped when it grows past this size
+const maxCachedColumns = 2048
 
-func newWorldGenerator(seed int64) *WorldGenerator {
+// Defines the block types for one chunk, indexed by x, height above the bottom of the world and z.
+type BlockTypes [chunkWidth][][chunkWidth]string
+
+// Creates a generator running the named stages in order.
+// Panics if a stage is not registered in generatorStages.
+func newWorldGenerator(seed int64, minHeight, maxHeight int, stages []string, structures *StructureLibrary) *WorldGenerator {
 	w := &WorldGenerator{}
+	w.seed = seed
+	w.minHeight = minHeight
+	w.maxHeight = maxHeight
+	w.structures = structures
 	w.noise = newNoiseMapGenerator()
 	w.noise.SetSeed(seed)
+	w.plans = map[featurePlanKey]*FeaturePlan{}
+	w.columns = map[[2]int]*ColumnNoise{}
+
+	for _, name := range stages {
+		newStage, ok := generatorStages[name]
+		if !ok {
+			log.Panicf("unknown generator stage %q", name)
+		}
+		w.stages = append(w.stages, newStage(w))
+	}
 	return w
 }
 
-// Generates the terrain for a chunk.
+// Generates the terrain for a chunk at the bottom of the world.
 // Returns the block types that can be used to initialize the chunk.
 func (w *WorldGenerator) Terrain(pos mgl32.Vec3) BlockTypes {
-	pos2d := mgl32.Vec2{pos.X(), pos.Z()}
-	biome := w.Biome(pos2d)
-	heights := w.Heights(pos2d)
-	out := newBlockTypes()
-	caves := w.Caves(pos)
-	gravel := w.Gravel(pos)
-
-	// set terrain
-	for x := 0; x < chunkWidth; x++ {
-		for y := chunkHeight - 1; y >= 0; y-- {
-			for z := 0; z < chunkWidth; z++ {
-				curHeight := pos.Y() + float32(y)
-				if curHeight > heights[x][z] {
-					// if this block is higher than the the
-					// height at this point in the terrain
-					continue
-				}
-
-				if curHeight <= 5 {
-					out[x][y][z] = "bedrock"
-					continue
-				}
-
-				if caves[x][y][z] > 0.725 {
-					continue
-				}
-
-				if gravel[x][y][z] > 0.77 {
-					if biome <= 0.4 {
-						out[x][y][z] = "sandstone"
-					} else {
-						if gravel[x][y][z] <= 0.775 {
-							out[x][y][z] = "gravel"
-						} else {
-							out[x][y][z] = "gravel2"
-						}
-					}
-					continue
-				}
-
-				if curHeight < 0.25 {
-					out[x][y][z] = "stone"
-					continue
-				}
-
-				if curHeight > 70 && biome >= 0.6 {
-					out[x][y][z] = "dirt-snow"
-					continue
-				}
-
-				// check if block is a stone
-				if y < chunkHeight-4 {
-					if y < int(heights[x][z]-4) {
-						if gravel[x][y][z] < 0.25 {
-							out[x][y][z] = "cobblestone"
-						} else {
-							out[x][y][z] = "stone"
-						}
-						continue
-					}
-				}
-
-				// define terrain based on biome
-				switch {
-				case biome <= 0.4:
-					out[x][y][z] = "sand"
-				case biome > 0.4 && biome < 0.7:
-					if y == int(heights[x][z]) {
-						out[x][y][z] = "dirt-grass"
-					} else {
-						out[x][y][z] = "dirt"
-					}
-				case biome >= 0.7:
-					out[x][y][z] = "dirt-wet-grass"
-				}
-			}
+	buf := newChunkBuffer(pos, w.maxHeight-w.minHeight, w.Biomes(mgl32.Vec2{pos.X(), pos.Z()}))
+	for _, stage := range w.stages {
+		stage.Generate(buf)
+	}
+	return buf.blocks
+}
+
+// Returns true if the generator runs the named stage.
+func (w *WorldGenerator) HasStage(name string) bool {
+	for _, stage := range w.stages {
+		if stage.Name() == name {
+			return true
 		}
 	}
+	return false
+}
 
-	return out
+// Returns a random number generator for a feature of a chunk.
+// The same seed, chunk position and salt always give the same sequence
+// so random features are generated identically every time the chunk spawns.
+func (w *WorldGenerator) ChunkRand(pos mgl32.Vec3, salt int64) *rand.Rand {
+	h := w.seed
+	for _, v := range []int64{int64(floor(pos.X())), int64(floor(pos.Y())), int64(floor(pos.Z())), salt} {
+		// mix each value in with the 64 bit fnv prime
+		h ^= v
+		h *= 1099511628211
+	}
+	return rand.New(rand.NewSource(h))
 }
 
-func (w *WorldGenerator) Biome(pos mgl32.Vec2) float32 {
-	biome := w.noise.OctaveNoise2D(pos.X(), pos.Y(), 0.0005, 0.0, 0, 1, true)
-	return normsigmoid(biome)
+const (
+	// climate noise
+	climateScale         = 0.002
+	continentalnessScale = 0.001
+	climateOctaves       = 3
+	climateContrast      = 2.5
+
+	// columns blend the heights of the biomes sampled on a grid around them
+	biomeBlendRadius = 12
+	biomeBlendStep   = 4
+
+	// terrain noise
+	terrainScale   = 0.01
+	terrainOctaves = 4
+
+	// cave noise
+	caveScale       = 0.1
+	caveOctaves     = 5
+	cavePersistence = 0.7
+)
+
+// Returns the climate at a column of the world.
+// Each parameter samples the same noise far from the others.
+func (w *WorldGenerator) Climate(x, z float32) Climate {
+	sample := func(x, z, scale float32) float32 {
+		noise := w.noise.OctaveNoise2D(x, z, scale, 0.5, 2, climateOctaves, true)
+
+		// the noise is concentrated around 0.5, spread it over [0,1]
+		return clamp((noise-0.5)*climateContrast+0.5, 0, 1)
+	}
+
+	return Climate{
+		temperature:     sample(x+10000, z, climateScale),
+		humidity:        sample(x, z+10000, climateScale),
+		continentalness: sample(x-10000, z-10000, continentalnessScale),
+	}
 }
 
-func (w *WorldGenerator) Heights(pos mgl32.Vec2) [][]float32 {
-	biome := w.Biome(pos)
-	flatHeights := w.FlatHeights(pos, 170)
-	mountHeights := w.MountainHeights(pos, 170)
-
-	// set the new heights in place in mountHeights
-	for i := 0; i < len(mountHeights); i++ {
-		for j := 0; j < len(mountHeights[0]); j++ {
-			targetHeight := exerp(normsigmoid(biome), flatHeights[i][j], mountHeights[i][j], 1.0)
-			mountHeights[i][j] = targetHeight
-		}
+// Returns the cached noise of the chunk column at chunk coordinates cx,cz.
+// The biomes are computed when the column is first sampled.
+func (w *WorldGenerator) column(cx, cz int) *ColumnNoise {
+	key := [2]int{cx, cz}
+	if c, ok := w.columns[key]; ok {
+		return c
+	}
+
+	if len(w.columns) >= maxCachedColumns {
+		clear(w.columns)
 	}
 
-	return mountHeights
+	c := &ColumnNoise{}
+	for x := range chunkWidth {
+		for z := range chunkWidth {
+			c.biomes[x][z] = selectBiome(w.Climate(float32(cx*chunkWidth+x), float32(cz*chunkWidth+z)))
+		}
+	}
+	w.columns[key] = c
+	return c
 }
 
-func (w *WorldGenerator) MountainHeights(pos mgl32.Vec2, height float32) [][]float32 {
-	config2D := NoiseConfig2D{
-		scale:       0.01,
-		normalize:   true,
-		width:       float32(chunkWidth),
-		height:      float32(chunkWidth),
-		position:    pos,
-		octaves:     8,
-		persistence: 1,
-		lacunarity:  1,
-		f: func(noise float32, i, j int) float32 {
-			return noise * height
-		},
+// Returns the terrain heights of the chunk column at chunk coordinates cx,cz.
+func (w *WorldGenerator) columnHeights(cx, cz int) *[chunkWidth][chunkWidth]float32 {
+	c := w.column(cx, cz)
+	if c.heights == nil {
+		heights := [chunkWidth][chunkWidth]float32{}
+		for x := range chunkWidth {
+			for z := range chunkWidth {
+				heights[x][z] = w.blendedHeight(float32(cx*chunkWidth+x), float32(cz*chunkWidth+z))
+			}
+		}
+		c.heights = &heights
 	}
-	return w.noise.Generate2D(config2D)
+	return c.heights
 }
 
-func (w *WorldGenerator) FlatHeights(pos mgl32.Vec2, height float32) [][]float32 {
-	config2D := NoiseConfig2D{
-		scale:       0.001,
-		normalize:   true,
-		width:       float32(chunkWidth),
-		height:      float32(chunkWidth),
-		position:    pos,
-		octaves:     4,
-		persistence: 0.7,
-		lacunarity:  2,
-		f: func(noise float32, i, j int) float32 {
-			return noise * height
-		},
+// Returns the biome at a column of the world.
+func (w *WorldGenerator) Biome(x, z float32) BiomeID {
+	bx, bz := int(floor(x)), int(floor(z))
+	return w.column(floorDiv(bx, chunkWidth), floorDiv(bz, chunkWidth)).biomes[bx-floorDiv(bx, chunkWidth)*chunkWidth][bz-floorDiv(bz, chunkWidth)*chunkWidth]
+}
+
+// Returns the biome of each column of the chunk at pos.
+func (w *WorldGenerator) Biomes(pos mgl32.Vec2) [chunkWidth][chunkWidth]BiomeID {
+	return w.column(floorDiv(int(floor(pos.X())), chunkWidth), floorDiv(int(floor(pos.Y())), chunkWidth)).biomes
+}
+
+// Returns the terrain height of each column of the chunk at pos.
+func (w *WorldGenerator) Heights(pos mgl32.Vec2) [][]float32 {
+	heights := w.columnHeights(floorDiv(int(floor(pos.X())), chunkWidth), floorDiv(int(floor(pos.Y())), chunkWidth))
+	out := make([][]float32, chunkWidth)
+	for x := range chunkWidth {
+		out[x] = heights[x][:]
 	}
-	return w.noise.Generate2D(config2D)
+	return out
 }
 
-func (w *WorldGenerator) TreeDistribution(pos mgl32.Vec2) [][]float32 {
-	biome := w.Biome(pos)
-	config2D := NoiseConfig2D{
-		scale:     0.5,
-		normalize: true,
-		width:     float32(chunkWidth),
-		height:    float32(chunkWidth),
-		position:  pos,
-		octaves:   1,
-		f: func(noise float32, i, j int) float32 {
-			// amplify trees when biome is high but
-			// maintain a certain level across all biomes
-			noise = float32(math.Pow(float64(noise), 4))
-			return biome*noise + 0.3
-		},
+// Returns the terrain height of a single column of the world.
+// Gives the same height as Heights, sharing its cache.
+func (w *WorldGenerator) Height(x, z float32) float32 {
+	bx, bz := int(floor(x)), int(floor(z))
+	cx, cz := floorDiv(bx, chunkWidth), floorDiv(bz, chunkWidth)
+	return w.columnHeights(cx, cz)[bx-cx*chunkWidth][bz-cz*chunkWidth]
+}
+
+// Returns the terrain height of a column from the biomes sampled on a grid around it.
+// The height parameters of the biomes are blended with weights
+// fading to zero at biomeBlendRadius to avoid cliffs at biome borders.
+func (w *WorldGenerator) blendedHeight(wx, wz float32) float32 {
+	// first grid line at or before a coordinate
+	gridStart := func(v float32) int {
+		return int(math.Floor(float64(v)/biomeBlendStep)) * biomeBlendStep
 	}
-	return w.noise.Generate2D(config2D)
+
+	var base, variation, total float32
+	for gx := gridStart(wx - biomeBlendRadius); float32(gx) <= wx+biomeBlendRadius; gx += biomeBlendStep {
+		for gz := gridStart(wz - biomeBlendRadius); float32(gz) <= wz+biomeBlendRadius; gz += biomeBlendStep {
+			d := mgl32.Vec2{float32(gx) - wx, float32(gz) - wz}.Len()
+			if d >= biomeBlendRadius {
+				continue
+			}
+
+			weight := (1 - d/biomeBlendRadius) * (1 - d/biomeBlendRadius)
+			b := w.Biome(float32(gx), float32(gz)).Biome()
+			base += b.baseHeight * weight
+			variation += b.heightVariation * weight
+			total += weight
+		}
+	}
+
+	noise := w.noise.OctaveNoise2D(wx, wz, terrainScale, 0.5, 2, terrainOctaves, true)
+	noise = clamp((noise-0.5)*climateContrast+0.5, 0, 1)
+	return (base + variation*noise) / total
 }
 
-func (w *WorldGenerator) TreeFallout(width, height, depth float32) [][][]float32 {
+// Returns the chance of keeping each leaf of a tree, fading away from the trunk.
+func (w *WorldGenerator) TreeFallout(width, height, depth float32) NoiseMap3D {
 	center := mgl32.Vec3{width / 2, 0, width / 2}
 	config := NoiseConfig3D{
 		width:     width,
@@ -204,28 +263,35 @@ func (w *WorldGenerator) TreeFallout(width, height, depth float32) [][][]float32
 	return w.noise.Generate3D(config)
 }
 
-func (w *WorldGenerator) Caves(pos mgl32.Vec3) [][][]float32 {
+func (w *WorldGenerator) Caves(pos mgl32.Vec3) NoiseMap3D {
 	config := NoiseConfig3D{
-		scale:       0.1,
+		scale:       caveScale,
 		normalize:   true,
 		width:       float32(chunkWidth),
-		height:      float32(chunkHeight),
+		height:      float32(w.maxHeight - w.minHeight),
 		depth:       chunkWidth,
 		position:    pos,
-		octaves:     5,
-		persistence: 0.7,
+		octaves:     caveOctaves,
+		persistence: cavePersistence,
 		lacunarity:  1,
 	}
 
 	return w.noise.Generate3D(config)
 }
 
-func (w *WorldGenerator) Gravel(pos mgl32.Vec3) [][][]float32 {
+// Returns true if the caves carve the block at a world position.
+// Gives the same result as Caves without generating the chunk.
+func (w *WorldGenerator) Cave(x, y, z int) bool {
+	noise := w.noise.OctaveNoise3D(float32(x), float32(y), float32(z), caveScale, cavePersistence, 1, caveOctaves, true)
+	return noise > caveThreshold
+}
+
+func (w *WorldGenerator) Gravel(pos mgl32.Vec3) NoiseMap3D {
 	config := NoiseConfig3D{
 		scale:     0.07,
 		normalize: true,
 		width:     float32(chunkWidth),
-		height:    float32(chunkHeight),
+		height:    float32(w.maxHeight - w.minHeight),
 		depth:     chunkWidth,
 		position:  pos,
 		octaves:   1,
@@ -0,0 +1,46 @@
+package game
+
+import "testing"
+
+// keeps the compiler from dropping the benchmarked calls
+var heightSink float32
+
+// Samples the height of every column of a chunk one by one, like the tree feature does.
+// Each operation of the cached benchmark reuses the columns sampled before,
+// the cold one starts from an empty cache and the uncached one empties it before every column.
+func BenchmarkColumnSampling(b *testing.B) {
+	sample := func(w *WorldGenerator, clearEach bool) {
+		for x := range chunkWidth {
+			for z := range chunkWidth {
+				if clearEach {
+					clear(w.columns)
+				}
+				heightSink += w.Height(float32(x), float32(z))
+			}
+		}
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		w := newWorldGenerator(1, 0, 256, nil, nil)
+		sample(w, false)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sample(w, false)
+		}
+	})
+
+	b.Run("cold", func(b *testing.B) {
+		w := newWorldGenerator(1, 0, 256, nil, nil)
+		for i := 0; i < b.N; i++ {
+			clear(w.columns)
+			sample(w, false)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		w := newWorldGenerator(1, 0, 256, nil, nil)
+		for i := 0; i < b.N; i++ {
+			sample(w, true)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+//go:build linux
+
+package game
+
+// #cgo pkg-config: egl
+// #include <stdlib.h>
+// #include <EGL/egl.h>
+// #include <EGL/eglext.h>
+//
+// static EGLDisplay surfacelessDisplay() {
+// 	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+// 		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+// 	if (getPlatformDisplay == NULL) {
+// 		return EGL_NO_DISPLAY;
+// 	}
+// 	return getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+// }
+//
+// static EGLContext coreContext(EGLDisplay display) {
+// 	EGLint attribs[] = {
+// 		EGL_CONTEXT_MAJOR_VERSION, 4,
+// 		EGL_CONTEXT_MINOR_VERSION, 1,
+// 		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+// 		EGL_CONTEXT_OPENGL_FORWARD_COMPATIBLE, EGL_TRUE,
+// 		EGL_NONE,
+// 	};
+// 	return eglCreateContext(display, EGL_NO_CONFIG_KHR, EGL_NO_CONTEXT, attribs);
+// }
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// OpenGL context without a window or a display server, drawn to framebuffers only.
+// Uses the surfaceless platform of Mesa, so software drivers like llvmpipe work on machines without a GPU.
+type HeadlessContext struct {
+	display C.EGLDisplay
+	context C.EGLContext
+}
+
+// Creates an OpenGL 4.1 core context and makes it current on the calling thread.
+func newHeadlessContext() (*HeadlessContext, error) {
+	runtime.LockOSThread()
+	display := C.surfacelessDisplay()
+	if display == C.EGLDisplay(C.EGL_NO_DISPLAY) {
+		return nil, fmt.Errorf("no surfaceless EGL display")
+	}
+	if C.eglInitialize(display, nil, nil) == C.EGL_FALSE {
+		return nil, fmt.Errorf("failed to initialize EGL: 0x%x", C.eglGetError())
+	}
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		C.eglTerminate(display)
+		return nil, fmt.Errorf("EGL has no OpenGL: 0x%x", C.eglGetError())
+	}
+
+	context := C.coreContext(display)
+	if context == C.EGLContext(C.EGL_NO_CONTEXT) {
+		C.eglTerminate(display)
+		return nil, fmt.Errorf("failed to create an OpenGL 4.1 context: 0x%x", C.eglGetError())
+	}
+	if C.eglMakeCurrent(display, C.EGLSurface(C.EGL_NO_SURFACE), C.EGLSurface(C.EGL_NO_SURFACE), context) == C.EGL_FALSE {
+		C.eglDestroyContext(display, context)
+		C.eglTerminate(display)
+		return nil, fmt.Errorf("failed to make the context current: 0x%x", C.eglGetError())
+	}
+
+	if err := gl.InitWithProcAddrFunc(getProcAddress); err != nil {
+		C.eglDestroyContext(display, context)
+		C.eglTerminate(display)
+		return nil, err
+	}
+	return &HeadlessContext{display, context}, nil
+}
+
+// Returns the address of an OpenGL function.
+func getProcAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return unsafe.Pointer(C.eglGetProcAddress(cname))
+}
+
+// Releases the context and the display.
+func (h *HeadlessContext) Terminate() {
+	C.eglMakeCurrent(h.display, C.EGLSurface(C.EGL_NO_SURFACE), C.EGLSurface(C.EGL_NO_SURFACE), C.EGLContext(C.EGL_NO_CONTEXT))
+	C.eglDestroyContext(h.display, h.context)
+	C.eglTerminate(h.display)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package game
+
+import "fmt"
+
+// OpenGL context without a window, only created through the surfaceless platform of Mesa on Linux.
+type HeadlessContext struct{}
+
+// Returns an error, captures fall back to a hidden window on this platform.
+func newHeadlessContext() (*HeadlessContext, error) {
+	return nil, fmt.Errorf("headless contexts are only supported on linux")
+}
+
+// Does nothing, there is no context to release.
+func (h *HeadlessContext) Terminate() {}
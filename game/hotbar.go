@@ -1,27 +1,52 @@
 package game
 
 import (
+	"strconv"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
 // Draws and maintains the selected hotbar and selected block.
+// The hotbar shows the first hotbarSize slots of the inventory.
 type Hotbar struct {
 	shader    *Shader
 	atlas     *TextureAtlas
 	camera    *Camera
-	bar       [9]string
+	inventory *Inventory
 	vertCount int
 	selected  int
 	vao       uint32
 	vbo       uint32
+
+	// state shown by the last buffer, the hotbar is only rebuffered when it changes
+	shownSlots    [hotbarSize]ItemStack
+	shownSelected int
 }
 
-func newHotbar(shader *Shader, atlas *TextureAtlas, camera *Camera) *Hotbar {
+const (
+	// layout (before projection)
+	hotbarSlotSpacing = 0.075
+	hotbarSlotScale   = 0.025
+	hotbarRow         = -0.35
+	hotbarMarkerRow   = -0.3
+
+	// size of the count glyphs relative to the slot
+	stackCountScale = 0.45
+
+	// depth of the count so it draws over the item
+	hotbarCountDepth = -0.1
+)
+
+// coords in texture atlas of the selected marker
+var selectedMarkerTexture = [2]int{43, 27}
+
+func newHotbar(shader *Shader, atlas *TextureAtlas, camera *Camera, inventory *Inventory) *Hotbar {
 	h := &Hotbar{
-		shader: shader,
-		atlas:  atlas,
-		camera: camera,
+		shader:    shader,
+		atlas:     atlas,
+		camera:    camera,
+		inventory: inventory,
 	}
 	return h
 }
@@ -50,111 +75,95 @@ func (h *Hotbar) Init() {
 // Sends the hotbar vertices to GPU.
 func (h *Hotbar) Buffer() {
 	gl.BindBuffer(gl.ARRAY_BUFFER, h.vbo)
-	h.vertCount = 0
 	buffer := []float32{}
 
-	idx := 0
-	for i := -4; i < 5; i++ {
+	for idx := range hotbarSize {
+		x := float32(idx-hotbarSize/2) * hotbarSlotSpacing
+		pos := mgl32.Vec2{x, hotbarRow}
+
 		// draw the inventory
-		var texFace [2]int
-		blockType := h.bar[idx]
-		if blockType != "" {
-			tex := blocks[h.bar[idx]]
-			texFace = tex[1]
+		stack := h.inventory.Slot(idx)
+		if !stack.Empty() {
+			buffer = appendScreenQuad(buffer, h.atlas, h.camera.projection, blocks[stack.blockType][1], pos, hotbarSlotScale, 0)
+			buffer = appendStackCount(buffer, h.atlas, h.camera.projection, stack, pos, hotbarSlotScale, hotbarCountDepth)
 		} else {
-			// coords in tecture atlas
-			texFace = [2]int{32, 6}
-		}
-
-		umin, umax, vmin, vmax := h.atlas.Coords(texFace[0], texFace[1])
-		quad := newQuad(umin, umax, vmin, vmax)
-
-		scale := mgl32.Scale3D(0.025, 0.025, 1)
-		translate := mgl32.Translate3D(float32(i)*0.075, -0.35, 0)
-		m := translate.Mul4(scale)
-		m = h.camera.projection.Mul4(m)
-		for _, v := range quad {
-			h.vertCount++
-			vert := m.Mul4x1(v.pos.Vec2().Vec4(0, 1))
-			buffer = append(buffer,
-				vert.X(), vert.Y(), 0,
-				v.tex.X(), v.tex.Y(),
-			)
+			buffer = appendScreenQuad(buffer, h.atlas, h.camera.projection, emptySlotTexture, pos, hotbarSlotScale, 0)
 		}
 
 		// draw a selected marker
 		if idx == h.selected {
-			umin, umax, vmin, vmax := h.atlas.Coords(43, 27)
-			quad := newQuad(umin, umax, vmin, vmax)
-			translate := mgl32.Translate3D(float32(i)*0.075, -0.3, 0)
-			m := translate.Mul4(scale)
-			m = h.camera.projection.Mul4(m)
-			for _, v := range quad {
-				h.vertCount++
-				vert := m.Mul4x1(v.pos.Vec2().Vec4(0, 1))
-				buffer = append(buffer,
-					vert.X(), vert.Y(), 0,
-					v.tex.X(), v.tex.Y(),
-				)
-			}
+			marker := mgl32.Vec2{x, hotbarMarkerRow}
+			buffer = appendScreenQuad(buffer, h.atlas, h.camera.projection, selectedMarkerTexture, marker, hotbarSlotScale, 0)
 		}
 
-		idx++
+		h.shownSlots[idx] = stack
 	}
+	h.shownSelected = h.selected
 
+	h.vertCount = len(buffer) / 5
 	gl.BufferData(gl.ARRAY_BUFFER, len(buffer)*4, gl.Ptr(buffer), gl.STATIC_DRAW)
 }
 
-// Adds a block to the hot bar.
-func (h *Hotbar) Add(blockType string) {
-	for i := range h.bar {
-		if h.bar[i] == blockType {
-			return
-		}
+// Returns true if the inventory or the selection changed since the last buffer.
+func (h *Hotbar) changed() bool {
+	if h.selected != h.shownSelected {
+		return true
 	}
-	for i := range h.bar {
-		if h.bar[i] == "" {
-			h.bar[i] = blockType
-			break
+
+	for idx := range hotbarSize {
+		if h.inventory.Slot(idx) != h.shownSlots[idx] {
+			return true
 		}
 	}
-	h.Buffer()
+	return false
 }
 
-func (h *Hotbar) AddAll(inventory map[string]int) {
-	for blockType := range inventory {
-		h.Add(blockType)
+// Appends the count of a stack at the bottom right corner of the slot at pos.
+// Single blocks don't show a count.
+func appendStackCount(buffer []float32, atlas *TextureAtlas, projection mgl32.Mat4, stack ItemStack, pos mgl32.Vec2, slotScale, depth float32) []float32 {
+	if stack.count <= 1 {
+		return buffer
 	}
-}
 
-// Removes a block from the hotbar.
-func (h *Hotbar) Remove(blockType string) {
-	for i := range h.bar {
-		if h.bar[i] == blockType {
-			h.bar[i] = ""
-			break
-		}
+	text := strconv.Itoa(stack.count)
+	scale := slotScale * stackCountScale
+	advance := 2 * scale * fontAdvance
+
+	// right align the last glyph (it is left aligned in its tile) with the slot
+	x := pos.X() + slotScale - scale/2 - float32(len(text)-1)*advance
+	y := pos.Y() - slotScale + scale
+	for i, c := range text {
+		glyphPos := mgl32.Vec2{x + float32(i)*advance, y}
+		buffer = appendScreenQuad(buffer, atlas, projection, atlas.Glyph(c), glyphPos, scale, depth)
 	}
-	h.Buffer()
+	return buffer
 }
 
-// Selects the ith item in the hotbar.
+// Selects the ith item in the hotbar, ignores slots outside the hotbar.
 func (h *Hotbar) Select(i int) {
+	if i < 0 || i >= hotbarSize {
+		return
+	}
 	h.selected = i
-	h.Buffer()
 }
 
-// Returns the selected block type.
-func (h *Hotbar) Selected() string {
-	if h.selected > -1 && h.selected < 10 {
-		return h.bar[h.selected]
-	}
-	return ""
+// Moves the selection by offset slots, wrapping around the ends of the hotbar.
+func (h *Hotbar) Scroll(offset int) {
+	h.selected = ((h.selected+offset)%hotbarSize + hotbarSize) % hotbarSize
+}
+
+// Returns the inventory slot of the selected item.
+func (h *Hotbar) Selected() int {
+	return h.selected
 }
 
-// Draws the hotbar on the screen.
+// Draws the hotbar on the screen, rebuffering it first if the shown state changed.
 // Does not apply view or model transformations because it is not world positioned.
 func (h *Hotbar) Draw() {
+	if h.changed() {
+		h.Buffer()
+	}
+
 	gl.UseProgram(h.shader.handle)
 	gl.BindVertexArray(h.vao)
 
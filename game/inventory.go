@@ -1,37 +1,157 @@
 package game
 
-// Holds count of held blocks.
+// Holds stacks of blocks in fixed slots.
+// The first hotbarSize slots are the ones shown in the hotbar.
 type Inventory struct {
-	// maps the blockType to count
-	content map[string]int
+	// slots in order, a slot without a block type is empty
+	slots [inventorySize]ItemStack
 }
 
+// A stack of blocks of the same type held in one slot.
+type ItemStack struct {
+	blockType string
+	count     int
+}
+
+const (
+	inventorySize    = 36
+	hotbarSize       = 9
+	defaultStackSize = 64
+)
+
 func newInventory() *Inventory {
-	i := &Inventory{}
-	i.content = make(map[string]int)
-	return i
+	return &Inventory{}
+}
+
+// Returns true if the stack holds nothing.
+func (s ItemStack) Empty() bool {
+	return s.blockType == "" || s.count <= 0
+}
+
+// Returns the max number of blocks of a type that fit in one slot.
+func stackSize(blockType string) int {
+	if size, ok := blockStackSizes[blockType]; ok {
+		return size
+	}
+	return defaultStackSize
+}
+
+// Adds blocks to the inventory.
+// Fills existing stacks of the same type first, then empty slots.
+// Returns the count that did not fit.
+func (i *Inventory) Add(blockType string, count int) int {
+	max := stackSize(blockType)
+	for s := range i.slots {
+		if count == 0 {
+			return 0
+		}
+
+		slot := &i.slots[s]
+		if slot.blockType == blockType && slot.count < max {
+			n := min(max-slot.count, count)
+			slot.count += n
+			count -= n
+		}
+	}
+
+	for s := range i.slots {
+		if count == 0 {
+			return 0
+		}
+
+		slot := &i.slots[s]
+		if slot.Empty() {
+			n := min(max, count)
+			*slot = ItemStack{blockType, n}
+			count -= n
+		}
+	}
+
+	return count
+}
+
+// Replaces the content of all the slots.
+func (i *Inventory) Set(slots [inventorySize]ItemStack) {
+	i.slots = slots
 }
 
-// Adds an item to the inventory.
-func (i *Inventory) Add(blockType string, count int) {
-	i.content[blockType] += count
+// Returns the stack held in a slot.
+func (i *Inventory) Slot(s int) ItemStack {
+	if s < 0 || s >= inventorySize {
+		return ItemStack{}
+	}
+	return i.slots[s]
+}
+
+// Sets the stack held in a slot.
+func (i *Inventory) SetSlot(s int, stack ItemStack) {
+	if stack.Empty() {
+		stack = ItemStack{}
+	}
+	i.slots[s] = stack
 }
 
-func (i *Inventory) Set(content map[string]int) {
-	i.content = content
+// Moves the stack at from into to.
+// Merges both stacks if they hold the same type, otherwise swaps them.
+// Returns what is left over in from.
+func (i *Inventory) Move(from, to int) ItemStack {
+	src, dst := i.slots[from], i.slots[to]
+	if from == to {
+		return src
+	}
+
+	if !dst.Empty() && dst.blockType == src.blockType {
+		n := min(stackSize(dst.blockType)-dst.count, src.count)
+		dst.count += n
+		src.count -= n
+		i.SetSlot(to, dst)
+		i.SetSlot(from, src)
+		return i.slots[from]
+	}
+
+	i.slots[from], i.slots[to] = dst, src
+	return dst
 }
 
-// Returns count of the item.
+// Returns count of the block type across all slots.
 func (i *Inventory) Count(blockType string) int {
-	return i.content[blockType]
+	total := 0
+	for _, slot := range i.slots {
+		if slot.blockType == blockType {
+			total += slot.count
+		}
+	}
+	return total
 }
 
-// Grabs a number of the selected blockType and returns true if amount was deducted.
+// Grabs a number of the block type from any slot and returns true if amount was deducted.
 func (i *Inventory) Grab(blockType string, count int) bool {
-	stock, exists := i.content[blockType]
-	if exists && stock >= count {
-		i.content[blockType] -= count
-		return true
+	if i.Count(blockType) < count {
+		return false
+	}
+
+	for s := len(i.slots) - 1; s >= 0 && count > 0; s-- {
+		slot := i.slots[s]
+		if slot.blockType != blockType {
+			continue
+		}
+
+		n := min(slot.count, count)
+		slot.count -= n
+		count -= n
+		i.SetSlot(s, slot)
 	}
-	return false
+	return true
+}
+
+// Grabs a number of blocks from a slot and returns true if amount was deducted.
+func (i *Inventory) GrabSlot(s int, count int) bool {
+	slot := i.Slot(s)
+	if slot.Empty() || slot.count < count {
+		return false
+	}
+
+	slot.count -= count
+	i.SetSlot(s, slot)
+	return true
 }
@@ -0,0 +1,345 @@
+package game
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Draws the full inventory grid and handles dragging stacks between slots with the mouse.
+// The bottom row of the grid holds the hotbar slots and the crafting grid sits above the inventory.
+// Screen slots are numbered with the inventory slots first, then the crafting cells and the crafting output.
+type InventoryScreen struct {
+	shader    *Shader
+	atlas     *TextureAtlas
+	camera    *Camera
+	inventory *Inventory
+	crafting  *CraftingGrid
+
+	// if the screen is currently shown
+	open bool
+
+	// stack picked up by the mouse and the slot it was picked from
+	held     ItemStack
+	heldFrom int
+
+	// drops the blocks that don't fit in the inventory as an item in the world
+	drop func(stack ItemStack)
+
+	// cursor position in normalized device coordinates
+	cursor mgl32.Vec2
+
+	// gpu info
+	vertCount int
+	vao, vbo  uint32
+}
+
+const (
+	// layout (before projection)
+	inventorySlotSpacing = 0.075
+	inventorySlotScale   = 0.025
+	inventoryItemScale   = 0.018
+	inventoryTop         = 0.1
+	inventoryHotbarRow   = -0.2
+	craftingTop          = 0.35
+
+	// screen slots after the inventory slots
+	craftingSlotStart  = inventorySize
+	craftingOutputSlot = craftingSlotStart + craftingGridSize*craftingGridSize
+	screenSlots        = craftingOutputSlot + 1
+
+	// depth of each layer so they draw over each other
+	inventorySlotDepth      = 0.0
+	inventoryItemDepth      = -0.1
+	inventoryCountDepth     = -0.2
+	inventoryHeldDepth      = -0.3
+	inventoryHeldCountDepth = -0.4
+)
+
+// coords in texture atlas of an empty slot
+var emptySlotTexture = [2]int{32, 6}
+
+func newInventoryScreen(shader *Shader, atlas *TextureAtlas, camera *Camera, inventory *Inventory, crafting *CraftingGrid, drop func(stack ItemStack)) *InventoryScreen {
+	s := &InventoryScreen{
+		shader:    shader,
+		atlas:     atlas,
+		camera:    camera,
+		inventory: inventory,
+		crafting:  crafting,
+		drop:      drop,
+	}
+	return s
+}
+
+// Initialize the inventory screen metadata on the GPU.
+func (s *InventoryScreen) Init() {
+	gl.UseProgram(s.shader.handle)
+
+	gl.GenVertexArrays(1, &s.vao)
+	gl.BindVertexArray(s.vao)
+	gl.GenBuffers(1, &s.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+
+	// configure the attributes
+	vertAttrib := uint32(gl.GetAttribLocation(s.shader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 3, gl.FLOAT, false, 5*4, 0)
+
+	texCoordAtrrib := uint32(gl.GetAttribLocation(s.shader.handle, gl.Str("texCoord\x00")))
+	gl.EnableVertexAttribArray(texCoordAtrrib)
+	gl.VertexAttribPointerWithOffset(texCoordAtrrib, 2, gl.FLOAT, false, 5*4, 3*4)
+
+	s.Buffer()
+}
+
+// Sends the inventory screen vertices to GPU.
+func (s *InventoryScreen) Buffer() {
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+	buffer := []float32{}
+
+	for slot := range screenSlots {
+		pos := s.slotPosition(slot)
+		buffer = appendScreenQuad(buffer, s.atlas, s.camera.projection, emptySlotTexture, pos, inventorySlotScale, inventorySlotDepth)
+
+		stack := s.stack(slot)
+		if !stack.Empty() {
+			buffer = appendScreenQuad(buffer, s.atlas, s.camera.projection, blocks[stack.blockType][1], pos, inventoryItemScale, inventoryItemDepth)
+			buffer = appendStackCount(buffer, s.atlas, s.camera.projection, stack, pos, inventorySlotScale, inventoryCountDepth)
+		}
+	}
+
+	// held stack follows the cursor
+	if !s.held.Empty() {
+		pos := s.unproject(s.cursor)
+		buffer = appendScreenQuad(buffer, s.atlas, s.camera.projection, blocks[s.held.blockType][1], pos, inventoryItemScale, inventoryHeldDepth)
+		buffer = appendStackCount(buffer, s.atlas, s.camera.projection, s.held, pos, inventorySlotScale, inventoryHeldCountDepth)
+	}
+
+	s.vertCount = len(buffer) / 5
+	gl.BufferData(gl.ARRAY_BUFFER, len(buffer)*4, gl.Ptr(buffer), gl.DYNAMIC_DRAW)
+}
+
+// Returns the stack shown in a screen slot.
+func (s *InventoryScreen) stack(slot int) ItemStack {
+	switch {
+	case slot == craftingOutputSlot:
+		return s.crafting.Result()
+	case slot >= craftingSlotStart:
+		return s.crafting.Slot(slot - craftingSlotStart)
+	default:
+		return s.inventory.Slot(slot)
+	}
+}
+
+// Sets the stack of a screen slot, the crafting output can't be set.
+func (s *InventoryScreen) setStack(slot int, stack ItemStack) {
+	switch {
+	case slot == craftingOutputSlot:
+		return
+	case slot >= craftingSlotStart:
+		s.crafting.SetSlot(slot-craftingSlotStart, stack)
+	default:
+		s.inventory.SetSlot(slot, stack)
+	}
+}
+
+// Returns the center of a slot before projection.
+func (s *InventoryScreen) slotPosition(slot int) mgl32.Vec2 {
+	if slot == craftingOutputSlot {
+		return mgl32.Vec2{2 * inventorySlotSpacing, craftingTop - inventorySlotSpacing}
+	}
+
+	if slot >= craftingSlotStart {
+		cell := slot - craftingSlotStart
+		return mgl32.Vec2{
+			float32(cell%craftingGridSize-craftingGridSize) * inventorySlotSpacing,
+			craftingTop - float32(cell/craftingGridSize)*inventorySlotSpacing,
+		}
+	}
+
+	if slot < hotbarSize {
+		return mgl32.Vec2{float32(slot-hotbarSize/2) * inventorySlotSpacing, inventoryHotbarRow}
+	}
+
+	row := (slot - hotbarSize) / hotbarSize
+	col := (slot - hotbarSize) % hotbarSize
+	return mgl32.Vec2{
+		float32(col-hotbarSize/2) * inventorySlotSpacing,
+		inventoryTop - float32(row)*inventorySlotSpacing,
+	}
+}
+
+// Reverses the projection of a position in normalized device coordinates.
+func (s *InventoryScreen) unproject(ndc mgl32.Vec2) mgl32.Vec2 {
+	return mgl32.Vec2{
+		ndc.X() / s.camera.projection.At(0, 0),
+		ndc.Y() / s.camera.projection.At(1, 1),
+	}
+}
+
+// Returns the slot under the position (in normalized device coordinates) or -1.
+func (s *InventoryScreen) slotAt(ndc mgl32.Vec2) int {
+	p := s.unproject(ndc)
+	for slot := range screenSlots {
+		d := p.Sub(s.slotPosition(slot))
+		if abs(d.X()) <= inventorySlotScale && abs(d.Y()) <= inventorySlotScale {
+			return slot
+		}
+	}
+	return -1
+}
+
+// Toggles the screen, returns true if it is now open.
+func (s *InventoryScreen) Toggle() bool {
+	if s.open {
+		s.Close()
+	} else {
+		s.open = true
+		s.Buffer()
+	}
+	return s.open
+}
+
+// Closes the screen, puts back any held stack and empties the crafting grid, dropping what doesn't fit in the inventory.
+func (s *InventoryScreen) Close() {
+	s.putBack()
+	s.crafting.Clear(s.inventory, s.drop)
+	s.open = false
+}
+
+// Moves the cursor to the screen coordinates.
+func (s *InventoryScreen) MoveCursor(screenX, screenY float32) {
+	s.cursor = mgl32.Vec2{
+		2*screenX/windowWidth - 1,
+		1 - 2*screenY/windowHeight,
+	}
+
+	if !s.held.Empty() {
+		s.Buffer()
+	}
+}
+
+// Picks up the stack under the cursor, or half of it if split is true.
+func (s *InventoryScreen) PickUp(split bool) {
+	slot := s.slotAt(s.cursor)
+	if !s.held.Empty() || slot < 0 {
+		return
+	}
+
+	// taking from the output crafts the result
+	if slot == craftingOutputSlot {
+		s.held = s.crafting.Craft()
+		s.heldFrom = slot
+		s.Buffer()
+		return
+	}
+
+	stack := s.stack(slot)
+	if stack.Empty() {
+		return
+	}
+
+	take := stack.count
+	if split {
+		take = (stack.count + 1) / 2
+	}
+
+	s.held = ItemStack{stack.blockType, take}
+	s.heldFrom = slot
+	stack.count -= take
+	s.setStack(slot, stack)
+	s.Buffer()
+}
+
+// Drops the held stack in the slot under the cursor.
+// Merges with a stack of the same type, swaps with another type
+// and goes back to its origin if there is no slot under the cursor.
+func (s *InventoryScreen) Drop() {
+	if s.held.Empty() {
+		return
+	}
+
+	to := s.slotAt(s.cursor)
+	if to < 0 || to == craftingOutputSlot {
+		s.putBack()
+		s.Buffer()
+		return
+	}
+
+	target := s.stack(to)
+	switch {
+	case target.Empty() || target.blockType == s.held.blockType:
+		n := min(stackSize(s.held.blockType)-target.count, s.held.count)
+		s.setStack(to, ItemStack{s.held.blockType, target.count + n})
+		s.held.count -= n
+		s.putBack()
+	case s.heldFrom != craftingOutputSlot && s.stack(s.heldFrom).Empty():
+		// swap with the other type
+		s.setStack(to, s.held)
+		s.setStack(s.heldFrom, target)
+		s.held = ItemStack{}
+	default:
+		s.putBack()
+	}
+
+	s.Buffer()
+}
+
+// Returns the held stack to the slot it was picked from if it is empty or holds the same type.
+// Crafted stacks and the blocks that don't fit in the origin go to the first slots with room in the inventory.
+func (s *InventoryScreen) putBack() {
+	if s.held.Empty() {
+		s.held = ItemStack{}
+		return
+	}
+
+	if s.heldFrom == craftingOutputSlot {
+		s.stash(s.held)
+		s.held = ItemStack{}
+		return
+	}
+
+	// the origin may have been filled by something else meanwhile
+	origin := s.stack(s.heldFrom)
+	if origin.Empty() || origin.blockType == s.held.blockType {
+		n := min(stackSize(s.held.blockType)-origin.count, s.held.count)
+		s.setStack(s.heldFrom, ItemStack{s.held.blockType, origin.count + n})
+		s.held.count -= n
+	}
+
+	s.stash(s.held)
+	s.held = ItemStack{}
+}
+
+// Adds a stack to the inventory, dropping what doesn't fit.
+func (s *InventoryScreen) stash(stack ItemStack) {
+	if stack.Empty() {
+		return
+	}
+
+	if left := s.inventory.Add(stack.blockType, stack.count); left > 0 {
+		s.drop(ItemStack{stack.blockType, left})
+	}
+}
+
+// Draws the inventory screen if it is open.
+// Does not apply view or model transformations because it is not world positioned.
+func (s *InventoryScreen) Draw() {
+	if !s.open {
+		return
+	}
+
+	gl.UseProgram(s.shader.handle)
+	gl.BindVertexArray(s.vao)
+
+	model := mgl32.Ident4()
+	modelUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("model\x00"))
+	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
+
+	texUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("tex\x00"))
+	gl.Uniform1i(texUniform, 0)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, s.atlas.texture.handle)
+
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(s.vertCount))
+}
@@ -0,0 +1,175 @@
+package game
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Item is a dropped stack of blocks lying in the world until it is picked up or despawns.
+// It is drawn as a small spinning cube textured like its block type.
+type Item struct {
+	atlas  *TextureAtlas
+	shader *Shader
+
+	// ref to rigid body gets updated by physics engine
+	body *RigidBody
+
+	// blocks held by this item
+	stack ItemStack
+
+	// the item can't be picked up before pickupAt and is removed after despawnAt
+	spawnedAt time.Time
+	pickupAt  time.Time
+	despawnAt time.Time
+
+	// gpu info
+	vertCount int
+	vao, vbo  uint32
+}
+
+const (
+	// dimensions
+	itemSize = 0.25
+	itemMass = 1
+
+	// interactions
+	itemPickupRadius   = 1.5
+	itemMergeRadius    = 1.0
+	itemPickupDelay    = time.Millisecond * 250
+	itemThrowDelay     = time.Millisecond * 1500
+	itemDespawnTimeout = time.Minute * 5
+	itemThrowSpeed     = 6
+
+	// animation
+	itemSpinSpeed   = 1.5  // radians per second
+	itemBobHeight   = 0.05 // blocks
+	itemBobDuration = 2.0  // seconds
+)
+
+func newItem(atlas *TextureAtlas, shader *Shader, stack ItemStack, initialPos, velocity mgl32.Vec3, pickupDelay time.Duration) *Item {
+	now := time.Now()
+	return &Item{
+		atlas:     atlas,
+		shader:    shader,
+		stack:     stack,
+		spawnedAt: now,
+		pickupAt:  now.Add(pickupDelay),
+		despawnAt: now.Add(itemDespawnTimeout),
+		body: &RigidBody{
+			name:                   "item",
+			mass:                   itemMass,
+			width:                  itemSize,
+			height:                 itemSize,
+			flying:                 false,
+			position:               initialPos,
+			velocity:               velocity,
+			bodyCollisionsDisabled: true,
+		},
+	}
+}
+
+// Initialize the item metadata on the GPU.
+func (it *Item) Init() {
+	vertices := []float32{}
+	for _, v := range blockVertices(it.atlas, it.stack.blockType, 0, [6]bool{}) {
+		vertices = append(vertices,
+			v.pos.X(), v.pos.Y(), v.pos.Z(), v.tex.X(), v.tex.Y(),
+		)
+	}
+	it.vertCount = len(vertices) / 5
+
+	gl.GenVertexArrays(1, &it.vao)
+	gl.BindVertexArray(it.vao)
+	gl.GenBuffers(1, &it.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, it.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+
+	vertAttrib := uint32(gl.GetAttribLocation(it.shader.handle, gl.Str("position\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 3, gl.FLOAT, false, 5*4, 0)
+
+	texAttrib := uint32(gl.GetAttribLocation(it.shader.handle, gl.Str("texCoords\x00")))
+	gl.EnableVertexAttribArray(texAttrib)
+	gl.VertexAttribPointerWithOffset(texAttrib, 2, gl.FLOAT, false, 5*4, 3*4)
+}
+
+// Deletes buffers from gpu.
+func (it *Item) Destroy() {
+	gl.DeleteBuffers(1, &it.vbo)
+	it.vbo = 0
+	gl.DeleteVertexArrays(1, &it.vao)
+	it.vao = 0
+}
+
+// Returns the center of the item.
+func (it *Item) Center() mgl32.Vec3 {
+	return it.body.position.Sub(mgl32.Vec3{0, itemSize / 2, 0})
+}
+
+// Returns true if the item can be picked up.
+func (it *Item) CanPickup() bool {
+	return time.Now().After(it.pickupAt)
+}
+
+// Returns true if the item lived past its timeout.
+func (it *Item) Expired() bool {
+	return time.Now().After(it.despawnAt)
+}
+
+// Returns true if some blocks of the other item can be merged into this one.
+func (it *Item) CanMerge(other *Item) bool {
+	return other.stack.blockType == it.stack.blockType &&
+		!other.stack.Empty() &&
+		it.stack.count < stackSize(it.stack.blockType)
+}
+
+// Merges another item of the same block type into this one as far as the stack size allows.
+// Returns true if the other item is now empty.
+func (it *Item) Merge(other *Item) bool {
+	if !it.CanMerge(other) {
+		return false
+	}
+
+	n := min(stackSize(it.stack.blockType)-it.stack.count, other.stack.count)
+	it.stack.count += n
+	other.stack.count -= n
+
+	// merging blocks restarts the despawn timer
+	if n > 0 {
+		it.despawnAt = time.Now().Add(itemDespawnTimeout)
+	}
+	return other.stack.Empty()
+}
+
+// Draws the item spinning and bobbing around its position.
+func (it *Item) Draw(camera *Camera) {
+	gl.UseProgram(it.shader.handle)
+	gl.BindVertexArray(it.vao)
+
+	age := time.Since(it.spawnedAt).Seconds()
+	bob := itemBobHeight * float32(math.Sin(2*math.Pi*age/itemBobDuration))
+	center := it.Center()
+
+	translate := mgl32.Translate3D(center.X(), center.Y()+bob, center.Z())
+	rotate := mgl32.HomogRotate3DY(float32(age * itemSpinSpeed))
+	scale := mgl32.Scale3D(itemSize, itemSize, itemSize)
+
+	model := translate.Mul4(rotate.Mul4(scale))
+	modelUniform := gl.GetUniformLocation(it.shader.handle, gl.Str("model\x00"))
+	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
+
+	view := camera.Mat()
+	viewUniform := gl.GetUniformLocation(it.shader.handle, gl.Str("view\x00"))
+	gl.UniformMatrix4fv(viewUniform, 1, false, &view[0])
+
+	textureUniform := gl.GetUniformLocation(it.shader.handle, gl.Str("tex\x00"))
+	gl.Uniform1i(textureUniform, 0)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, it.atlas.texture.handle)
+
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(it.vertCount))
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestItemMerge(t *testing.T) {
+	full := stackSize("stone")
+	tests := []struct {
+		name           string
+		item, other    ItemStack
+		merged         bool
+		count, left    int
+		refreshDespawn bool
+	}{
+		{"same type", ItemStack{"stone", 3}, ItemStack{"stone", 2}, true, 5, 0, true},
+		{"up to the stack size", ItemStack{"stone", full - 1}, ItemStack{"stone", 3}, false, full, 2, true},
+		{"full stack", ItemStack{"stone", full}, ItemStack{"stone", 3}, false, full, 3, false},
+		{"other type", ItemStack{"stone", 3}, ItemStack{"dirt", 2}, false, 3, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := newItem(nil, nil, tt.item, mgl32.Vec3{}, mgl32.Vec3{}, 0)
+			other := newItem(nil, nil, tt.other, mgl32.Vec3{}, mgl32.Vec3{}, 0)
+			despawnAt := time.Now()
+			item.despawnAt = despawnAt
+
+			if got := item.Merge(other); got != tt.merged {
+				t.Fatalf("Merge = %v, want %v", got, tt.merged)
+			}
+			if item.stack.count != tt.count || other.stack.count != tt.left {
+				t.Fatalf("counts after merge %d and %d, want %d and %d", item.stack.count, other.stack.count, tt.count, tt.left)
+			}
+			if refreshed := item.despawnAt.After(despawnAt); refreshed != tt.refreshDespawn {
+				t.Fatalf("despawn timer refreshed = %v, want %v", refreshed, tt.refreshDespawn)
+			}
+		})
+	}
+}
+
+// Returns a game with a player at the origin and the inventory screen, without gpu or database.
+func newItemTestGame() (*Game, *[]ItemStack) {
+	dropped := &[]ItemStack{}
+	g := &Game{}
+	g.player = newPlayer(mgl32.Vec3{})
+	g.items = map[*Item]bool{}
+	g.crafting = newCraftingGrid(newRecipeBook("../recipes"))
+	g.inventoryScreen = newInventoryScreen(nil, nil, g.player.camera, g.player.inventory, g.crafting, func(stack ItemStack) {
+		*dropped = append(*dropped, stack)
+	})
+	return g, dropped
+}
+
+func TestHeldStackKeptWhenPickingUpItems(t *testing.T) {
+	g, dropped := newItemTestGame()
+	inventory := g.player.inventory
+
+	// hold the only stack of the inventory, its slot is now the first empty one
+	inventory.SetSlot(0, ItemStack{"stone", 10})
+	g.inventoryScreen.open = true
+	g.inventoryScreen.held = inventory.Slot(0)
+	g.inventoryScreen.heldFrom = 0
+	inventory.SetSlot(0, ItemStack{})
+
+	// an item of another type lies at the feet of the player
+	feet := g.player.body.position.Sub(mgl32.Vec3{0, playerHeight / 2, 0})
+	item := newItem(nil, nil, ItemStack{"dirt", 3}, feet, mgl32.Vec3{}, 0)
+	item.pickupAt = time.Time{}
+	g.items[item] = true
+
+	g.UpdateItems()
+	if !g.items[item] || item.stack.count != 3 {
+		t.Fatalf("item picked up while the inventory screen is open, %d left", item.stack.count)
+	}
+
+	g.inventoryScreen.Close()
+	if s := inventory.Slot(0); s != (ItemStack{"stone", 10}) {
+		t.Fatalf("slot of the held stack has %v after closing, want 10 stone", s)
+	}
+	if len(*dropped) > 0 {
+		t.Fatalf("dropped %v", *dropped)
+	}
+}
+
+func TestPutBackIntoFilledOrigin(t *testing.T) {
+	full := stackSize("stone")
+	tests := []struct {
+		name   string
+		origin ItemStack
+		held   ItemStack
+		want   map[string]int
+	}{
+		{"empty origin", ItemStack{}, ItemStack{"stone", 10}, map[string]int{"stone": 10}},
+		{"other type", ItemStack{"dirt", 3}, ItemStack{"stone", 10}, map[string]int{"stone": 10, "dirt": 3}},
+		{"same type over the stack size", ItemStack{"stone", full - 2}, ItemStack{"stone", 10}, map[string]int{"stone": full + 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, dropped := newItemTestGame()
+			inventory := g.player.inventory
+			inventory.SetSlot(0, tt.origin)
+			g.inventoryScreen.held = tt.held
+			g.inventoryScreen.heldFrom = 0
+
+			g.inventoryScreen.Close()
+			for blockType, count := range tt.want {
+				if got := inventory.Count(blockType); got != count {
+					t.Fatalf("%d %s in the inventory, want %d", got, blockType, count)
+				}
+			}
+			for s := range inventorySize {
+				if stack := inventory.Slot(s); stack.count > stackSize(stack.blockType) {
+					t.Fatalf("slot %d holds %v, over the stack size", s, stack)
+				}
+			}
+			if len(*dropped) > 0 {
+				t.Fatalf("dropped %v", *dropped)
+			}
+		})
+	}
+}
+
+func TestPutBackDropsWhenInventoryFull(t *testing.T) {
+	g, dropped := newItemTestGame()
+	inventory := g.player.inventory
+	for s := range inventorySize {
+		inventory.SetSlot(s, ItemStack{"dirt", stackSize("dirt")})
+	}
+	g.inventoryScreen.held = ItemStack{"stone", 10}
+	g.inventoryScreen.heldFrom = 0
+
+	g.inventoryScreen.Close()
+	if len(*dropped) != 1 || (*dropped)[0] != (ItemStack{"stone", 10}) {
+		t.Fatalf("dropped %v, want 10 stone", *dropped)
+	}
+}
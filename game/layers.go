@@ -0,0 +1,51 @@
+package game
+
+// Render layers.
+// Opaque blocks hide the faces behind them and are drawn first.
+// Cutout blocks (e.g. leaves) have fully transparent holes in their texture, they are drawn with the opaque
+// blocks but the faces behind them stay visible. Translucent blocks (e.g. water, ice) are blended over
+// the terrain afterwards, from the farthest to the nearest, and hide the faces between blocks of their type.
+
+// RenderLayer is the pass a block is drawn in.
+type RenderLayer int
+
+const (
+	opaqueLayer RenderLayer = iota
+	cutoutLayer
+	translucentLayer
+)
+
+// Render layers by block type, blocks not listed are opaque.
+var blockLayers = map[string]RenderLayer{
+	"leaves":        cutoutLayer,
+	"leaves-flower": cutoutLayer,
+	"cactus":        cutoutLayer,
+	"dandelion":     cutoutLayer,
+	"cornflower":    cutoutLayer,
+	"white-flower":  cutoutLayer,
+	"mushroom":      cutoutLayer,
+	"ice":           translucentLayer,
+}
+
+// Returns the render layer of the block, translucent fluids are drawn in the translucent layer.
+func (b *Block) RenderLayer() RenderLayer {
+	if fluid := b.Fluid(); fluid != nil && fluid.translucent {
+		return translucentLayer
+	}
+	return blockLayers[b.blockType]
+}
+
+// Returns true if the block is a solid cube that can't be seen through.
+// Only opaque blocks hide the faces next to them, stop light and block the view of the sections behind them.
+func (b *Block) Opaque() bool {
+	return b.Solid() && b.RenderLayer() == opaqueLayer && b.Shape() == cubeShape
+}
+
+// Returns true if the face of the block towards the neighbour n is hidden by it.
+// Faces between blocks of the same fluid or translucent type are hidden so only their surface is seen.
+func (b *Block) FaceHidden(n *Block) bool {
+	if n.Opaque() {
+		return true
+	}
+	return n.active && n.blockType == b.blockType && (b.Fluid() != nil || b.RenderLayer() == translucentLayer)
+}
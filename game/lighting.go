@@ -0,0 +1,426 @@
+package game
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Voxel lighting.
+// Every block holds the light reaching it from the sky and from emitting blocks (e.g. glowstone).
+// Light spreads to the neighbouring blocks letting it through losing a level per block,
+// except full sky light which shines straight down.
+// It is flood filled when a chunk spawns and updated around a changed block
+// by removing the light the block was feeding and spreading it again from the border.
+// Sections without blocks don't store light, they are lit by the full sky if the section above
+// lets it down their column and dark otherwise.
+
+const maxLightLevel = 15
+
+// Light levels emitted by block types.
+var blockEmissions = map[string]int{
+	"glowstone": 15,
+	"lamp":      14,
+	"lava":      15,
+}
+
+// Kind of light, each spreads on its own.
+type lightChannel int
+
+const (
+	skyChannel lightChannel = iota
+	blockChannel
+)
+
+var lightChannels = []lightChannel{skyChannel, blockChannel}
+
+// Integer offsets of the directions (ordered like directions).
+var lightOffsets = [6][3]int{
+	{0, 0, -1},
+	{0, 0, 1},
+	{0, -1, 0},
+	{0, 1, 0},
+	{-1, 0, 0},
+	{1, 0, 0},
+}
+
+// A block position with the level of light it spreads.
+type lightNode struct {
+	x, y, z int
+	level   int
+}
+
+// Returns the light level of the block in the channel.
+func (b *Block) light(ch lightChannel) int {
+	if ch == skyChannel {
+		return int(b.skyLight)
+	}
+	return int(b.blockLight)
+}
+
+// Sets the light level of the block in the channel.
+func (b *Block) setLight(ch lightChannel, level int) {
+	if ch == skyChannel {
+		b.skyLight = uint8(level)
+	} else {
+		b.blockLight = uint8(level)
+	}
+}
+
+// Returns the light levels absorbed by the block, on top of the level lost per block.
+// Opaque blocks stop light, cutout blocks dim it and translucent blocks let it through.
+// Blocks that aren't full cubes absorb the light of their shape.
+func (b *Block) LightOpacity() int {
+	if !b.active {
+		return 0
+	}
+	if fluid := b.Fluid(); fluid != nil {
+		return fluid.lightOpacity
+	}
+	if shape := b.Shape(); shape != cubeShape {
+		return shape.lightOpacity
+	}
+
+	switch b.RenderLayer() {
+	case cutoutLayer:
+		return 1
+	case translucentLayer:
+		return 0
+	}
+	return maxLightLevel
+}
+
+// Returns the light level emitted by the block.
+func (b *Block) Emission() int {
+	if !b.active {
+		return 0
+	}
+	return blockEmissions[b.blockType]
+}
+
+// Returns the world coordinates of the block.
+func (b *Block) worldCoords() (x, y, z int) {
+	return int(b.chunk.pos.X()) + b.i, int(b.chunk.pos.Y()) + b.j, int(b.chunk.pos.Z()) + b.k
+}
+
+// Returns the sky and block light levels at the world coordinates.
+// Positions above the world or in chunks not spawned are lit by the sky, positions below the world are dark.
+func (w *World) Light(x, y, z int) (sky, block int) {
+	if y < w.minHeight {
+		return 0, 0
+	}
+	if y >= w.maxHeight {
+		return maxLightLevel, 0
+	}
+
+	chunkPos, i, j, k := w.Position(mgl32.Vec3{float32(x), float32(y), float32(z)})
+	chunk := w.chunks.Get(chunkPos)
+	if chunk == nil {
+		return maxLightLevel, 0
+	}
+	return chunk.lightAt(i, j, k)
+}
+
+// Lights a newly spawned chunk.
+// The sky shines down each column until a block absorbs it, then the sky light, the emitting blocks
+// and the light of the spawned neighbours spread through the chunk and back into the neighbours.
+func (w *World) LightChunk(c *Chunk) {
+	a := newLightAccess(w)
+	x0, y0, z0 := int(c.pos.X()), int(c.pos.Y()), int(c.pos.Z())
+
+	var sky, block []lightNode
+	for i := range chunkWidth {
+		for k := range chunkWidth {
+			open := true
+			for j := c.Height() - 1; j >= 0; j-- {
+				b := c.existingBlock(i, j, k)
+				if b == nil {
+					continue
+				}
+
+				x, y, z := x0+i, y0+j, z0+k
+				b.blockLight = uint8(b.Emission())
+				if b.blockLight > 0 {
+					block = append(block, lightNode{x, y, z, int(b.blockLight)})
+				}
+
+				b.skyLight = 0
+				if !open {
+					continue
+				}
+
+				// the first block absorbing light ends the column, the rest is spread sideways
+				level := maxLightLevel - b.LightOpacity()
+				if level < maxLightLevel {
+					open = false
+				}
+				if level > 0 {
+					b.skyLight = uint8(level)
+					sky = append(sky, lightNode{x, y, z, level})
+				}
+			}
+		}
+	}
+
+	// blocks of the neighbouring chunks along the border
+	neighbours := func(visit func(x, z int)) {
+		for n := range chunkWidth {
+			visit(x0-1, z0+n)
+			visit(x0+chunkWidth, z0+n)
+			visit(x0+n, z0-1)
+			visit(x0+n, z0+chunkWidth)
+		}
+	}
+	neighbours(func(x, z int) {
+		for j := range c.Height() {
+			y := y0 + j
+			b, ok := a.block(x, y, z)
+			if !ok {
+				if a.open(x, y, z) {
+					sky = append(sky, lightNode{x, y, z, maxLightLevel})
+				}
+				continue
+			}
+
+			if b.skyLight > 0 {
+				sky = append(sky, lightNode{x, y, z, int(b.skyLight)})
+			}
+			if b.blockLight > 0 {
+				block = append(block, lightNode{x, y, z, int(b.blockLight)})
+			}
+		}
+	})
+
+	a.spread(sky, skyChannel)
+	a.spread(block, blockChannel)
+
+	// the border faces of the neighbours were lit as if the sky was shining through this chunk
+	neighbours(func(x, z int) {
+		n := a.chunkAt(x, z)
+		if n == nil {
+			return
+		}
+
+		i, k := min(max(x-x0, 0), chunkWidth-1), min(max(z-z0, 0), chunkWidth-1)
+		for j := range c.Height() {
+			b := c.existingBlock(i, j, k)
+			if b != nil && !b.Opaque() && (b.skyLight != maxLightLevel || b.blockLight != 0) {
+				a.invalidate(n, j)
+			}
+		}
+	})
+}
+
+// Updates the light around a block that changed, e.g. placed, broken or filled by a fluid.
+func (w *World) UpdateLight(b *Block) {
+	a := newLightAccess(w)
+	x, y, z := b.worldCoords()
+	for _, ch := range lightChannels {
+		removed := []lightNode{{x, y, z, b.light(ch)}}
+		b.setLight(ch, 0)
+		a.changed(b)
+		seeds := a.unspread(removed, ch)
+
+		// the block takes the light of its neighbours again, unless it stops light
+		for _, o := range lightOffsets {
+			nx, ny, nz := x+o[0], y+o[1], z+o[2]
+			if n, ok := a.block(nx, ny, nz); ok {
+				if level := n.light(ch); level > 0 {
+					seeds = append(seeds, lightNode{nx, ny, nz, level})
+				}
+			} else if ch == skyChannel && a.open(nx, ny, nz) {
+				seeds = append(seeds, lightNode{nx, ny, nz, maxLightLevel})
+			}
+		}
+
+		if e := b.Emission(); ch == blockChannel && e > 0 {
+			b.setLight(ch, e)
+			seeds = append(seeds, lightNode{x, y, z, e})
+		}
+		a.spread(seeds, ch)
+	}
+}
+
+// Looks up the blocks reached by a light update and rebuffers the sections it changed.
+// Chunks are kept by column coordinates since updates look up the same few chunks many times.
+type lightAccess struct {
+	w *World
+
+	// chunks looked up by column coordinates, nil if not spawned
+	chunks map[[2]int]*Chunk
+
+	// last chunk looked up
+	chunk  *Chunk
+	cx, cz int
+	cached bool
+
+	// last chunk marked as changed
+	changedChunk *Chunk
+}
+
+func newLightAccess(w *World) *lightAccess {
+	return &lightAccess{w: w, chunks: make(map[[2]int]*Chunk)}
+}
+
+// Returns the chunk holding the world column x,z, nil if it is not spawned.
+func (a *lightAccess) chunkAt(x, z int) *Chunk {
+	cx, cz := floorDiv(x, chunkWidth), floorDiv(z, chunkWidth)
+	if a.cached && a.cx == cx && a.cz == cz {
+		return a.chunk
+	}
+
+	c, ok := a.chunks[[2]int{cx, cz}]
+	if !ok {
+		pos := mgl32.Vec3{float32(cx * chunkWidth), float32(a.w.minHeight), float32(cz * chunkWidth)}
+		c = a.w.chunks.Get(pos)
+		a.chunks[[2]int{cx, cz}] = c
+	}
+	a.chunk, a.cx, a.cz, a.cached = c, cx, cz, true
+	return c
+}
+
+// Returns the block at the world coordinates and true if it stores light.
+// Returns false outside the spawned chunks and the world height and in sections without blocks.
+func (a *lightAccess) block(x, y, z int) (*Block, bool) {
+	j := y - a.w.minHeight
+	if j < 0 || y >= a.w.maxHeight {
+		return nil, false
+	}
+
+	c := a.chunkAt(x, z)
+	if c == nil {
+		return nil, false
+	}
+
+	b := c.existingBlock(x-a.cx*chunkWidth, j, z-a.cz*chunkWidth)
+	return b, b != nil
+}
+
+// Returns true if the sky shines at the world coordinates without light being stored,
+// above the world or in a section without blocks of a spawned chunk that the sky reaches.
+func (a *lightAccess) open(x, y, z int) bool {
+	if y >= a.w.maxHeight {
+		return true
+	}
+	if y < a.w.minHeight {
+		return false
+	}
+
+	c := a.chunkAt(x, z)
+	j := y - a.w.minHeight
+	return c != nil && c.sections[j/chunkSectionSize] == nil && c.skyOpen(x-a.cx*chunkWidth, j, z-a.cz*chunkWidth)
+}
+
+// Marks the chunk section at height j and its neighbours to be rebuffered at the end of the tick.
+func (a *lightAccess) invalidate(c *Chunk, j int) {
+	c.Invalidate(j)
+	if c != a.changedChunk {
+		a.w.changedChunks[c] = true
+		a.changedChunk = c
+	}
+}
+
+// Marks the sections showing the light of the block to be rebuffered.
+// Faces are lit by the block in front of them so the light of opaque blocks is never shown,
+// blocks on the chunk border light the faces of the neighbouring chunk.
+func (a *lightAccess) changed(b *Block) {
+	if b.Opaque() {
+		return
+	}
+	a.invalidate(b.chunk, b.j)
+
+	x, _, z := b.worldCoords()
+	visit := func(x, z int) {
+		if c := a.chunkAt(x, z); c != nil {
+			a.invalidate(c, b.j)
+		}
+	}
+	if b.i == 0 {
+		visit(x-1, z)
+	}
+	if b.i == chunkWidth-1 {
+		visit(x+1, z)
+	}
+	if b.k == 0 {
+		visit(x, z-1)
+	}
+	if b.k == chunkWidth-1 {
+		visit(x, z+1)
+	}
+}
+
+// Spreads the light of the nodes in the channel to the blocks around them.
+// Nodes storing light spread the level they have now, which removals may have changed since they were queued.
+func (a *lightAccess) spread(queue []lightNode, ch lightChannel) {
+	for n := 0; n < len(queue); n++ {
+		node := queue[n]
+		if b, ok := a.block(node.x, node.y, node.z); ok {
+			node.level = b.light(ch)
+		}
+		if node.level == 0 {
+			continue
+		}
+
+		for d, o := range lightOffsets {
+			x, y, z := node.x+o[0], node.y+o[1], node.z+o[2]
+			b, ok := a.block(x, y, z)
+			if !ok {
+				continue
+			}
+
+			opacity := b.LightOpacity()
+			if opacity >= maxLightLevel {
+				continue
+			}
+
+			level := node.level - 1 - opacity
+			if ch == skyChannel && Direction(d) == down && node.level == maxLightLevel {
+				level = maxLightLevel - opacity
+			}
+			if level <= b.light(ch) {
+				continue
+			}
+
+			b.setLight(ch, level)
+			a.changed(b)
+			queue = append(queue, lightNode{x, y, z, level})
+		}
+	}
+}
+
+// Removes the light spread by the nodes in the channel, each node holding the level it had.
+// Returns the nodes lit from elsewhere found around the removed light, to be spread again.
+func (a *lightAccess) unspread(queue []lightNode, ch lightChannel) []lightNode {
+	var seeds []lightNode
+	for n := 0; n < len(queue); n++ {
+		node := queue[n]
+		for d, o := range lightOffsets {
+			x, y, z := node.x+o[0], node.y+o[1], node.z+o[2]
+			b, ok := a.block(x, y, z)
+			if !ok {
+				if ch == skyChannel && a.open(x, y, z) {
+					seeds = append(seeds, lightNode{x, y, z, maxLightLevel})
+				}
+				continue
+			}
+
+			level := b.light(ch)
+			if level == 0 {
+				continue
+			}
+
+			// brighter blocks are lit by something else
+			fed := level < node.level || ch == skyChannel && Direction(d) == down && node.level == maxLightLevel
+			if !fed {
+				seeds = append(seeds, lightNode{x, y, z, level})
+				continue
+			}
+
+			b.setLight(ch, 0)
+			a.changed(b)
+			queue = append(queue, lightNode{x, y, z, level})
+
+			if e := b.Emission(); ch == blockChannel && e > 0 {
+				b.setLight(ch, e)
+				seeds = append(seeds, lightNode{x, y, z, e})
+			}
+		}
+	}
+	return seeds
+}
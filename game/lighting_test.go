@@ -0,0 +1,153 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Adds a chunk filled by fill and lights it like spawning it does.
+func addLitChunk(w *World, pos mgl32.Vec3, fill func(i, j, k int) string) *Chunk {
+	c := addTestChunk(w, pos, fill)
+	c.discoverBlock = w.ExistingBlock
+	c.discoverLight = w.Light
+	w.LightChunk(c)
+	return c
+}
+
+// Returns a fill of stone up to the height with an air tunnel along x at y 10 and z 4.
+// The blocks of the tunnel from x0 are air and the one at x0 is glowstone if lit.
+func tunnel(height, x0 int, lit bool) func(i, j, k int) string {
+	return func(i, j, k int) string {
+		switch {
+		case j > height:
+			return ""
+		case j != 10 || k != 4:
+			return "stone"
+		case i == x0 && lit:
+			return "glowstone"
+		}
+		return ""
+	}
+}
+
+func TestSkyLightDownColumn(t *testing.T) {
+	tests := []struct {
+		name string
+		roof bool
+		want int
+	}{
+		{"open", false, maxLightLevel},
+		{"under a roof", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+			addLitChunk(w, mgl32.Vec3{}, func(i, j, k int) string {
+				if j == 0 || j == 40 && (tt.roof || i == 4 && k == 4) {
+					return "stone"
+				}
+				return ""
+			})
+
+			// the sky shines straight down to the block and is spread around it below
+			if sky, _ := w.Light(4, 41, 4); sky != maxLightLevel {
+				t.Fatalf("sky light above the block is %d, want %d", sky, maxLightLevel)
+			}
+			if sky, _ := w.Light(4, 40, 4); sky != 0 {
+				t.Fatalf("sky light in the block is %d, want 0", sky)
+			}
+			if !tt.roof {
+				if sky, _ := w.Light(4, 39, 4); sky != maxLightLevel-1 {
+					t.Fatalf("sky light under the block is %d, want %d", sky, maxLightLevel-1)
+				}
+				if sky, _ := w.Light(5, 39, 4); sky != maxLightLevel {
+					t.Fatalf("sky light beside the block is %d, want %d", sky, maxLightLevel)
+				}
+			}
+
+			// the section without blocks below is only lit if the sky reaches it
+			if sky, _ := w.Light(8, 20, 8); sky != tt.want {
+				t.Fatalf("sky light in the empty section is %d, want %d", sky, tt.want)
+			}
+			if sky, _ := w.Light(8, 1, 8); sky != tt.want {
+				t.Fatalf("sky light on the floor is %d, want %d", sky, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockLightFades(t *testing.T) {
+	w := newTestWorld()
+	addLitChunk(w, mgl32.Vec3{}, tunnel(20, 1, true))
+
+	for x := 1; x < chunkWidth; x++ {
+		sky, block := w.Light(x, 10, 4)
+		if want := maxLightLevel - (x - 1); block != want {
+			t.Fatalf("block light %d blocks from the glowstone is %d, want %d", x-1, block, want)
+		}
+		if sky != 0 {
+			t.Fatalf("sky light in the tunnel at %d is %d, want 0", x, sky)
+		}
+	}
+
+	// stone stops the light
+	if _, block := w.Light(2, 11, 4); block != 0 {
+		t.Fatalf("block light in the stone is %d, want 0", block)
+	}
+}
+
+func TestBlockLightRemovedWithEmitter(t *testing.T) {
+	w := newTestWorld()
+	c := addLitChunk(w, mgl32.Vec3{}, tunnel(20, 1, true))
+
+	b := c.existingBlock(1, 10, 4)
+	b.active = false
+	w.UpdateLight(b)
+
+	for x := 1; x < chunkWidth; x++ {
+		if _, block := w.Light(x, 10, 4); block != 0 {
+			t.Fatalf("block light at %d is %d after breaking the glowstone, want 0", x, block)
+		}
+	}
+
+	// placing it back lights the tunnel again
+	b.active = true
+	w.UpdateLight(b)
+	if _, block := w.Light(5, 10, 4); block != maxLightLevel-4 {
+		t.Fatalf("block light at 5 is %d after placing the glowstone, want %d", block, maxLightLevel-4)
+	}
+}
+
+func TestBlockLightCrossesChunkBorder(t *testing.T) {
+	// glowstone at x 12 of the first chunk, the tunnel goes on in the second
+	first, second := tunnel(20, 12, true), tunnel(20, -1, false)
+
+	tests := []struct {
+		name  string
+		order []int
+	}{
+		{"emitter spawned first", []int{0, 1}},
+		{"emitter spawned last", []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+			for _, n := range tt.order {
+				if n == 0 {
+					addLitChunk(w, mgl32.Vec3{}, first)
+				} else {
+					addLitChunk(w, mgl32.Vec3{chunkWidth, 0, 0}, second)
+				}
+			}
+
+			for x := 12; x < 12+maxLightLevel; x++ {
+				if _, block := w.Light(x, 10, 4); block != maxLightLevel-(x-12) {
+					t.Fatalf("block light at %d is %d, want %d", x, block, maxLightLevel-(x-12))
+				}
+			}
+		})
+	}
+}
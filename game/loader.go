@@ -0,0 +1,149 @@
+package game
+
+import (
+	"container/heap"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ChunkLoader orders the loading of the chunks requested around the player.
+// The nearest chunks load first and the chunks out of view wait for the ones in view.
+// Each chunk is pending at most once and loads no longer requested are dropped.
+type ChunkLoader struct {
+	// pending loads, the lowest priority first
+	queue chunkLoadQueue
+
+	// pending loads by chunk position
+	pending map[mgl32.Vec3]*chunkLoad
+
+	// request passes made, loads not requested in the last pass are dropped
+	pass int
+
+	metrics ChunkLoaderMetrics
+}
+
+// ChunkLoaderMetrics counts the work of the loader for the debug overlay.
+type ChunkLoaderMetrics struct {
+	// chunks waiting to be loaded
+	pending int
+
+	// highest number of pending chunks
+	peak int
+
+	// loads handed out and pending loads dropped since the world was loaded
+	loaded, dropped int
+}
+
+// A pending chunk load.
+type chunkLoad struct {
+	pos mgl32.Vec3
+
+	// loads with a lower priority load first
+	priority float32
+
+	// pass the load was last requested in
+	pass int
+
+	// index in the queue
+	index int
+}
+
+// chunks out of view are loaded as if they were this many times farther
+const outOfViewPriorityFactor = 3
+
+func newChunkLoader() *ChunkLoader {
+	return &ChunkLoader{
+		pending: make(map[mgl32.Vec3]*chunkLoad),
+	}
+}
+
+// Starts a pass of requests.
+// Should be followed by a Request for each missing chunk and a call to EndPass.
+func (l *ChunkLoader) BeginPass() {
+	l.pass++
+}
+
+// Requests the chunk at pos to be loaded, at the distance from the player.
+// Requesting a pending chunk again updates its priority.
+func (l *ChunkLoader) Request(pos mgl32.Vec3, distance float32, inView bool) {
+	priority := distance
+	if !inView {
+		priority *= outOfViewPriorityFactor
+	}
+
+	if load, ok := l.pending[pos]; ok {
+		load.pass = l.pass
+		if load.priority != priority {
+			load.priority = priority
+			heap.Fix(&l.queue, load.index)
+		}
+		return
+	}
+
+	load := &chunkLoad{pos: pos, priority: priority, pass: l.pass}
+	l.pending[pos] = load
+	heap.Push(&l.queue, load)
+	l.metrics.peak = max(l.metrics.peak, len(l.pending))
+}
+
+// Ends a pass of requests, dropping the pending loads that were not requested again.
+func (l *ChunkLoader) EndPass() {
+	for pos, load := range l.pending {
+		if load.pass != l.pass {
+			heap.Remove(&l.queue, load.index)
+			delete(l.pending, pos)
+			l.metrics.dropped++
+		}
+	}
+	l.metrics.pending = len(l.pending)
+}
+
+// Returns the position of the next chunk to load.
+// Returns false if nothing is pending.
+func (l *ChunkLoader) Pop() (mgl32.Vec3, bool) {
+	if len(l.queue) == 0 {
+		return mgl32.Vec3{}, false
+	}
+
+	load := heap.Pop(&l.queue).(*chunkLoad)
+	delete(l.pending, load.pos)
+	l.metrics.pending = len(l.pending)
+	l.metrics.loaded++
+	return load.pos, true
+}
+
+// Returns the metrics of the loader.
+func (l *ChunkLoader) Metrics() ChunkLoaderMetrics {
+	return l.metrics
+}
+
+// Priority queue of chunk loads implementing heap.Interface.
+type chunkLoadQueue []*chunkLoad
+
+func (q chunkLoadQueue) Len() int {
+	return len(q)
+}
+
+func (q chunkLoadQueue) Less(i, j int) bool {
+	return q[i].priority < q[j].priority
+}
+
+func (q chunkLoadQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *chunkLoadQueue) Push(x any) {
+	load := x.(*chunkLoad)
+	load.index = len(*q)
+	*q = append(*q, load)
+}
+
+func (q *chunkLoadQueue) Pop() any {
+	old := *q
+	load := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return load
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A chunk load requested from the loader.
+type loadRequest struct {
+	x        int
+	distance float32
+	inView   bool
+}
+
+// Returns the position of the chunk x chunks along the x axis.
+func loadPos(x int) mgl32.Vec3 {
+	return mgl32.Vec3{float32(x * chunkWidth), 0, 0}
+}
+
+// Returns the x of the chunks popped from the loader until nothing is pending.
+func popAll(l *ChunkLoader) []int {
+	var order []int
+	for {
+		pos, ok := l.Pop()
+		if !ok {
+			return order
+		}
+		order = append(order, int(pos.X())/chunkWidth)
+	}
+}
+
+func TestChunkLoaderOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []loadRequest
+		want     []int
+	}{
+		{"nearest first", []loadRequest{{0, 30, true}, {1, 10, true}, {2, 20, true}}, []int{1, 2, 0}},
+		{"in view first", []loadRequest{{0, 10, false}, {1, 20, true}}, []int{1, 0}},
+		{"near out of view before far in view", []loadRequest{{0, 10, false}, {1, 40, true}}, []int{0, 1}},
+		{"duplicate loaded once", []loadRequest{{0, 10, true}, {0, 10, true}, {1, 20, true}}, []int{0, 1}},
+		{"request again updates the priority", []loadRequest{{0, 10, true}, {1, 20, true}, {0, 30, true}}, []int{1, 0}},
+		{"request again in view", []loadRequest{{0, 10, false}, {1, 20, true}, {0, 10, true}}, []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newChunkLoader()
+			l.BeginPass()
+			for _, r := range tt.requests {
+				l.Request(loadPos(r.x), r.distance, r.inView)
+			}
+			l.EndPass()
+
+			if m := l.Metrics(); m.pending != len(tt.want) {
+				t.Fatalf("%d pending loads, want %d", m.pending, len(tt.want))
+			}
+			if got := popAll(l); !slices.Equal(got, tt.want) {
+				t.Fatalf("loaded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkLoaderDropsStaleLoads(t *testing.T) {
+	tests := []struct {
+		name    string
+		passes  [][]int
+		want    []int
+		dropped int
+	}{
+		{"requested again", [][]int{{0, 1}, {0, 1}}, []int{0, 1}, 0},
+		{"not requested again", [][]int{{0, 1, 2}, {1}}, []int{1}, 2},
+		{"requested in a later pass", [][]int{{0}, {1}, {0, 1}}, []int{0, 1}, 1},
+		{"empty pass", [][]int{{0, 1}, {}}, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newChunkLoader()
+			for _, pass := range tt.passes {
+				l.BeginPass()
+				for _, x := range pass {
+					l.Request(loadPos(x), float32(x), true)
+				}
+				l.EndPass()
+			}
+
+			if got := popAll(l); !slices.Equal(got, tt.want) {
+				t.Fatalf("loaded %v, want %v", got, tt.want)
+			}
+			if m := l.Metrics(); m.dropped != tt.dropped {
+				t.Fatalf("%d loads dropped, want %d", m.dropped, tt.dropped)
+			}
+		})
+	}
+}
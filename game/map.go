@@ -49,6 +49,11 @@ func (v *SpatialMap[T]) All() []*T {
 	return out
 }
 
+// Returns the number of objects.
+func (v *SpatialMap[T]) Len() int {
+	return len(v.m)
+}
+
 // Serializes the coordinate.
 func (v *SpatialMap[T]) serialize(p mgl32.Vec3) string {
 	return fmt.Sprintf("%f_%f_%f", p.X(), p.Y(), p.Z())
@@ -16,6 +16,11 @@ func ceil(v float32) float32 {
 	return float32(math.Ceil(float64(v)))
 }
 
+// 32 bit absolute value.
+func abs(v float32) float32 {
+	return float32(math.Abs(float64(v)))
+}
+
 // Returns the sign of the passed input.
 func sign(x float32) float32 {
 	if x > 0 {
@@ -57,3 +62,23 @@ func acos(x float32) float32 {
 func angleBetween(v1, v2 mgl32.Vec3) float32 {
 	return acos(v1.Dot(v2) / (v1.Len() * v2.Len()))
 }
+
+// Returns v limited to the range [lo, hi].
+func clamp(v, lo, hi float32) float32 {
+	return min(max(v, lo), hi)
+}
+
+// Returns 0 below lo, 1 above hi and a smooth curve in between.
+func smoothstep(lo, hi, v float32) float32 {
+	t := clamp((v-lo)/(hi-lo), 0, 1)
+	return t * t * (3 - 2*t)
+}
+
+// Returns the quotient of a by b rounded down, also for negative values.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
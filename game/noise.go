@@ -6,15 +6,42 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-// Generates perlin noise used for terrain generation.
+// Generates noise used for terrain generation.
+// Perlin or OpenSimplex2 noise is combined over octaves into fbm, ridged or billow fractals,
+// optionally sampled at coordinates warped by another noise.
 type NoiseMapGenerator struct {
 	perm []int
 	seed int64
 }
 
+// Base noise sampled at each octave.
+type NoiseType int
+
+const (
+	noisePerlin NoiseType = iota
+	noiseSimplex
+)
+
+// How the octaves of a noise are combined.
+type FractalType int
+
+const (
+	// sum of the octaves
+	fractalFBM FractalType = iota
+
+	// sharp crests where the noise crosses zero, for mountain ranges
+	fractalRidged
+
+	// rounded bumps, for hills
+	fractalBillow
+)
+
 // Configurers a 3D generation.
+// Zero values of the noise, fractal and warp select perlin fbm without warping.
 type NoiseConfig3D struct {
 	f           func(noise float32, i, j, k int) float32
+	noise       NoiseType
+	fractal     FractalType
 	octaves     int
 	position    mgl32.Vec3
 	scale       float32
@@ -24,11 +51,18 @@ type NoiseConfig3D struct {
 	height      float32
 	depth       float32
 	normalize   bool
+
+	// the coordinates are moved by up to warp blocks by simplex noise of warpScale
+	warp      float32
+	warpScale float32
 }
 
 // Configurers a 2D generation.
+// Zero values of the noise, fractal and warp select perlin fbm without warping.
 type NoiseConfig2D struct {
 	f           func(noise float32, i, j int) float32
+	noise       NoiseType
+	fractal     FractalType
 	octaves     int
 	position    mgl32.Vec2
 	scale       float32
@@ -37,8 +71,32 @@ type NoiseConfig2D struct {
 	width       float32
 	height      float32
 	normalize   bool
+
+	// the coordinates are moved by up to warp blocks by simplex noise of warpScale
+	warp      float32
+	warpScale float32
+}
+
+// NoiseMap3D holds a generated 3D noise map in a flat slice.
+type NoiseMap3D struct {
+	values               []float32
+	width, height, depth int
+}
+
+// NoiseMap2D holds a generated 2D noise map in a flat slice.
+type NoiseMap2D struct {
+	values        []float32
+	width, height int
 }
 
+const (
+	// octaves of the noise warping the coordinates
+	warpOctaves = 2
+
+	// distance between the noise samples warping each axis so they are unrelated
+	warpOffset = 5000
+)
+
 func newNoiseMapGenerator() *NoiseMapGenerator {
 	return &NoiseMapGenerator{}
 }
@@ -49,79 +107,108 @@ func (n *NoiseMapGenerator) SetSeed(seed int64) {
 	n.perm = n.generatePermutation()
 }
 
+// Returns the noise at i,j,k of the map.
+func (m NoiseMap3D) At(i, j, k int) float32 {
+	return m.values[(i*m.height+j)*m.depth+k]
+}
+
+// Returns the noise at i,j of the map.
+func (m NoiseMap2D) At(i, j int) float32 {
+	return m.values[i*m.height+j]
+}
+
 // Generates a 3D noise map with the given configuration.
-func (n *NoiseMapGenerator) Generate3D(config NoiseConfig3D) [][][]float32 {
-	o := make([][][]float32, 0)
-	idx := 0
-	for i := int(floor(config.position.X())); i < int(floor(config.width+config.position.X())); i++ {
-		o = append(o, make([][]float32, 0))
-		jdx := 0
-		for j := int(floor(config.position.Y())); j < int(floor(config.height+config.position.Y())); j++ {
-			o[idx] = append(o[idx], make([]float32, 0))
-			kdx := 0
-			for k := int(floor(config.position.Z())); k < int(floor(config.depth+config.position.Z())); k++ {
-				noise := n.OctaveNoise3D(
-					float32(i),
-					float32(j),
-					float32(k),
-					config.scale,
-					config.persistence,
-					config.lacunarity,
-					config.octaves,
-					config.normalize,
-				)
+func (n *NoiseMapGenerator) Generate3D(config NoiseConfig3D) NoiseMap3D {
+	x0, y0, z0 := int(floor(config.position.X())), int(floor(config.position.Y())), int(floor(config.position.Z()))
+	m := NoiseMap3D{
+		width:  int(floor(config.width+config.position.X())) - x0,
+		height: int(floor(config.height+config.position.Y())) - y0,
+		depth:  int(floor(config.depth+config.position.Z())) - z0,
+	}
+	m.values = make([]float32, 0, m.width*m.height*m.depth)
 
+	for i := range m.width {
+		for j := range m.height {
+			for k := range m.depth {
+				noise := n.Sample3D(float32(x0+i), float32(y0+j), float32(z0+k), config)
 				if config.f != nil {
-					noise = config.f(noise, idx, jdx, kdx)
+					noise = config.f(noise, i, j, k)
 				}
-				o[idx][jdx] = append(o[idx][jdx], noise)
-				kdx++
+				m.values = append(m.values, noise)
 			}
-			jdx++
 		}
-		idx++
 	}
-	return o
+	return m
 }
 
 // Generates a 2D noise map with the given configuration.
-func (n *NoiseMapGenerator) Generate2D(config NoiseConfig2D) [][]float32 {
-	idx := 0
-	jdx := 0
-	o := make([][]float32, 0)
-	for i := int(floor(config.position.X())); i < int(floor(config.width+config.position.X())); i++ {
-		o = append(o, make([]float32, 0))
-		jdx = 0
-		for j := int(floor(config.position.Y())); j < int(floor(config.height+config.position.Y())); j++ {
-			noise := n.OctaveNoise2D(
-				float32(j),
-				float32(i),
-				config.scale,
-				config.persistence,
-				config.lacunarity,
-				config.octaves,
-				config.normalize,
-			)
+func (n *NoiseMapGenerator) Generate2D(config NoiseConfig2D) NoiseMap2D {
+	x0, y0 := int(floor(config.position.X())), int(floor(config.position.Y()))
+	m := NoiseMap2D{
+		width:  int(floor(config.width+config.position.X())) - x0,
+		height: int(floor(config.height+config.position.Y())) - y0,
+	}
+	m.values = make([]float32, 0, m.width*m.height)
+
+	for i := range m.width {
+		for j := range m.height {
+			noise := n.Sample2D(float32(x0+i), float32(y0+j), config)
 			if config.f != nil {
-				noise = config.f(noise, idx, jdx)
+				noise = config.f(noise, i, j)
 			}
-			o[idx] = append(o[idx], noise)
-			jdx++
+			m.values = append(m.values, noise)
 		}
-		idx++
 	}
-	return o
+	return m
+}
+
+// Returns the noise of the configuration at a single point, ignoring its position and size.
+func (n *NoiseMapGenerator) Sample3D(x, y, z float32, config NoiseConfig3D) float32 {
+	if config.warp != 0 {
+		dx := n.fractal3D(noiseSimplex, fractalFBM, x+warpOffset, y, z, config.warpScale, 0.5, 2, warpOctaves, false)
+		dy := n.fractal3D(noiseSimplex, fractalFBM, x, y+warpOffset, z, config.warpScale, 0.5, 2, warpOctaves, false)
+		dz := n.fractal3D(noiseSimplex, fractalFBM, x, y, z+warpOffset, config.warpScale, 0.5, 2, warpOctaves, false)
+		x, y, z = x+dx*config.warp, y+dy*config.warp, z+dz*config.warp
+	}
+	return n.fractal3D(config.noise, config.fractal, x, y, z, config.scale, config.persistence, config.lacunarity, config.octaves, config.normalize)
 }
 
-// Wrapper over perlinNoise3D to compute noise from octaves, persistence and lacunarity.
+// Returns the noise of the configuration at a single point, ignoring its position and size.
+func (n *NoiseMapGenerator) Sample2D(x, y float32, config NoiseConfig2D) float32 {
+	if config.warp != 0 {
+		dx := n.fractal2D(noiseSimplex, fractalFBM, x+warpOffset, y, config.warpScale, 0.5, 2, warpOctaves, false)
+		dy := n.fractal2D(noiseSimplex, fractalFBM, x, y+warpOffset, config.warpScale, 0.5, 2, warpOctaves, false)
+		x, y = x+dx*config.warp, y+dy*config.warp
+	}
+	return n.fractal2D(config.noise, config.fractal, x, y, config.scale, config.persistence, config.lacunarity, config.octaves, config.normalize)
+}
+
+// Perlin fbm noise from octaves, persistence and lacunarity.
 func (n *NoiseMapGenerator) OctaveNoise3D(x, y, z, scale, persistence, lacunarity float32, octaves int, normalize bool) float32 {
+	return n.fractal3D(noisePerlin, fractalFBM, x, y, z, scale, persistence, lacunarity, octaves, normalize)
+}
+
+// Perlin fbm noise from octaves, persistence and lacunarity.
+func (n *NoiseMapGenerator) OctaveNoise2D(x, y, scale, persistence, lacunarity float32, octaves int, normalize bool) float32 {
+	return n.fractal2D(noisePerlin, fractalFBM, x, y, scale, persistence, lacunarity, octaves, normalize)
+}
+
+// Combines octaves of the noise into a fractal in [-1,1], or [0,1] when normalized.
+func (n *NoiseMapGenerator) fractal3D(t NoiseType, f FractalType, x, y, z, scale, persistence, lacunarity float32, octaves int, normalize bool) float32 {
 	total := float32(0)
 	frequency := float32(1)
 	amplitude := float32(1)
 	maxValue := float32(0)
 
 	for i := 0; i < octaves; i++ {
-		total += n.perlinNoise3D(x*scale*frequency, y*scale*frequency, z*scale*frequency, n.perm) * amplitude
+		var noise float32
+		if t == noiseSimplex {
+			// each octave has its own seed so they don't line up at the origin
+			noise = simplexNoise3D(n.seed+int64(i), float64(x*scale*frequency), float64(y*scale*frequency), float64(z*scale*frequency))
+		} else {
+			noise = n.perlinNoise3D(x*scale*frequency, y*scale*frequency, z*scale*frequency, n.perm)
+		}
+		total += fractalOctave(f, noise) * amplitude
 
 		maxValue += amplitude
 		amplitude *= persistence
@@ -136,15 +223,22 @@ func (n *NoiseMapGenerator) OctaveNoise3D(x, y, z, scale, persistence, lacunarit
 	return out
 }
 
-// Wrapper over perlinNoise2D to compute noise from octaves, persistence and lacunarity.
-func (n *NoiseMapGenerator) OctaveNoise2D(x, y, scale, persistence, lacunarity float32, octaves int, normalize bool) float32 {
+// Combines octaves of the noise into a fractal in [-1,1], or [0,1] when normalized.
+func (n *NoiseMapGenerator) fractal2D(t NoiseType, f FractalType, x, y, scale, persistence, lacunarity float32, octaves int, normalize bool) float32 {
 	total := float32(0)
 	frequency := float32(1)
 	amplitude := float32(1)
 	maxValue := float32(0)
 
 	for i := 0; i < octaves; i++ {
-		total += n.perlinNoise2D(x*scale*frequency, y*scale*frequency, n.perm) * amplitude
+		var noise float32
+		if t == noiseSimplex {
+			noise = simplexNoise2D(n.seed+int64(i), float64(x*scale*frequency), float64(y*scale*frequency))
+		} else {
+			noise = n.perlinNoise2D(x*scale*frequency, y*scale*frequency, n.perm)
+		}
+		total += fractalOctave(f, noise) * amplitude
+
 		maxValue += amplitude
 		amplitude *= persistence
 		frequency *= lacunarity
@@ -158,6 +252,19 @@ func (n *NoiseMapGenerator) OctaveNoise2D(x, y, scale, persistence, lacunarity f
 	return out
 }
 
+// Shapes the noise of one octave in [-1,1] for the fractal type.
+func fractalOctave(f FractalType, noise float32) float32 {
+	switch f {
+	case fractalRidged:
+		ridge := 1 - abs(noise)
+		return ridge*ridge*2 - 1
+	case fractalBillow:
+		return abs(noise)*2 - 1
+	default:
+		return noise
+	}
+}
+
 func (n *NoiseMapGenerator) perlinNoise3D(x, y, z float32, perm []int) float32 {
 	// get grid point
 	x0 := floor(x)
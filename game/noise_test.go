@@ -0,0 +1,58 @@
+package game
+
+import "testing"
+
+// keeps the compiler from dropping the benchmarked calls
+var noiseSink float32
+
+// Each operation samples every block of a chunk section, or every column of a chunk in 2D,
+// at the scale of the terrain noise.
+const noiseBenchScale = 0.01
+
+func BenchmarkPerlin2D(b *testing.B) {
+	n := newNoiseMapGenerator()
+	n.SetSeed(1)
+	for i := 0; i < b.N; i++ {
+		for x := range chunkWidth {
+			for z := range chunkWidth {
+				noiseSink += n.perlinNoise2D(float32(x)*noiseBenchScale, float32(z+i)*noiseBenchScale, n.perm)
+			}
+		}
+	}
+}
+
+func BenchmarkPerlin3D(b *testing.B) {
+	n := newNoiseMapGenerator()
+	n.SetSeed(1)
+	for i := 0; i < b.N; i++ {
+		for x := range chunkWidth {
+			for y := range chunkSectionSize {
+				for z := range chunkWidth {
+					noiseSink += n.perlinNoise3D(float32(x)*noiseBenchScale, float32(y)*noiseBenchScale, float32(z+i)*noiseBenchScale, n.perm)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSimplex2D(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for x := range chunkWidth {
+			for z := range chunkWidth {
+				noiseSink += simplexNoise2D(1, float64(x)*noiseBenchScale, float64(z+i)*noiseBenchScale)
+			}
+		}
+	}
+}
+
+func BenchmarkSimplex3D(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for x := range chunkWidth {
+			for y := range chunkSectionSize {
+				for z := range chunkWidth {
+					noiseSink += simplexNoise3D(1, float64(x)*noiseBenchScale, float64(y)*noiseBenchScale, float64(z+i)*noiseBenchScale)
+				}
+			}
+		}
+	}
+}
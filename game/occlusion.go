@@ -0,0 +1,184 @@
+package game
+
+// Occlusion culling of the chunk sections.
+// Each section records which of its faces are connected through non opaque blocks.
+// The visible sections are found by walking from the section of the camera to its neighbours,
+// only leaving a section by a face connected to the one it was entered from, never going back
+// towards the camera and staying in the frustrum. Sections behind walls of terrain are never reached.
+
+// A section reached by the walk.
+type sectionStep struct {
+	chunk *Chunk
+
+	// index of the section in the chunk
+	section int
+
+	// face the section was entered from, noDirection for the section of the camera
+	from Direction
+
+	// directions taken since the section of the camera
+	taken uint8
+}
+
+// Computes the pairs of faces of the section connected through non opaque blocks.
+// Flood fills the non opaque blocks and connects all the faces reached by each fill.
+func (s *ChunkSection) computeConnections() {
+	s.connections = 0
+
+	var visited [chunkWidth][chunkSectionSize][chunkWidth]bool
+	stack := make([][3]int, 0, chunkWidth*chunkSectionSize*chunkWidth)
+	for i := range chunkWidth {
+		for j := range chunkSectionSize {
+			for k := range chunkWidth {
+				if visited[i][j][k] || s.blocks[i][j][k].Opaque() {
+					continue
+				}
+
+				// faces touched by this open region
+				var faces uint8
+				visited[i][j][k] = true
+				stack = append(stack[:0], [3]int{i, j, k})
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					faces |= sectionFaces(p[0], p[1], p[2])
+
+					for _, d := range directions {
+						x, y, z := p[0]+int(d.X()), p[1]+int(d.Y()), p[2]+int(d.Z())
+						if x < 0 || x >= chunkWidth || y < 0 || y >= chunkSectionSize || z < 0 || z >= chunkWidth {
+							continue
+						}
+						if visited[x][y][z] || s.blocks[x][y][z].Opaque() {
+							continue
+						}
+
+						visited[x][y][z] = true
+						stack = append(stack, [3]int{x, y, z})
+					}
+				}
+
+				for a := range directions {
+					for b := range directions {
+						if faces&(1<<a) != 0 && faces&(1<<b) != 0 {
+							s.connections |= 1 << (a*len(directions) + b)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns the faces of the section touched by the block at i,j,k.
+func sectionFaces(i, j, k int) uint8 {
+	var faces uint8
+	if k == 0 {
+		faces |= 1 << north
+	}
+	if k == chunkWidth-1 {
+		faces |= 1 << south
+	}
+	if j == 0 {
+		faces |= 1 << down
+	}
+	if j == chunkSectionSize-1 {
+		faces |= 1 << up
+	}
+	if i == 0 {
+		faces |= 1 << west
+	}
+	if i == chunkWidth-1 {
+		faces |= 1 << east
+	}
+	return faces
+}
+
+// Returns true if something can be seen through the section from face a to face b.
+// Sections changed since they were buffered are open in every direction until their connections are computed.
+func (s *ChunkSection) Connected(a, b Direction) bool {
+	if s.dirty {
+		return true
+	}
+	return s.connections&(1<<(int(a)*len(directions)+int(b))) != 0
+}
+
+// Marks the sections of the chunks visible from the camera.
+// Returns the chunks with at least one visible section.
+// When the camera is outside the world height every section in the frustrum is visible.
+func (w *World) MarkVisibleSections(chunks []*Chunk, camera *Camera) []*Chunk {
+	frustrum := camera.Frustrum()
+	candidates := make(map[*Chunk]bool, len(chunks))
+	for _, c := range chunks {
+		candidates[c] = true
+		for idx, s := range c.sections {
+			if s != nil {
+				s.visible = !w.InHeight(camera.pos) && frustrum.Intersects(c.SectionBox(idx))
+			}
+		}
+	}
+
+	chunkPos, _, j, _ := w.Position(camera.pos)
+	start := w.chunks.Get(chunkPos)
+	if w.InHeight(camera.pos) && start != nil && candidates[start] {
+		w.walkSections(sectionStep{start, j / chunkSectionSize, noDirection, 0}, candidates, frustrum)
+	}
+
+	out := make([]*Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		for _, s := range c.sections {
+			if s != nil && s.visible {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Walks the sections from the first step breadth first, marking the sections reached as visible.
+// Empty sections are open in every direction.
+func (w *World) walkSections(first sectionStep, candidates map[*Chunk]bool, frustrum *Frustrum) {
+	type sectionKey struct {
+		chunk   *Chunk
+		section int
+	}
+	visited := map[sectionKey]bool{{first.chunk, first.section}: true}
+
+	queue := []sectionStep{first}
+	for len(queue) > 0 {
+		step := queue[0]
+		queue = queue[1:]
+
+		section := step.chunk.sections[step.section]
+		if section != nil {
+			section.visible = true
+		}
+
+		for i, d := range directions {
+			dir := Direction(i)
+
+			// never walk back towards the camera
+			if step.taken&(1<<dir.Opposite()) != 0 {
+				continue
+			}
+			if step.from != noDirection && section != nil && !section.Connected(step.from, dir) {
+				continue
+			}
+
+			next := sectionStep{step.chunk, step.section + int(d.Y()), dir.Opposite(), step.taken | 1<<dir}
+			if d.Y() == 0 {
+				next.chunk = w.chunks.Get(step.chunk.pos.Add(d.Mul(chunkWidth)))
+			}
+			if next.chunk == nil || !candidates[next.chunk] || next.section < 0 || next.section >= len(next.chunk.sections) {
+				continue
+			}
+
+			key := sectionKey{next.chunk, next.section}
+			if visited[key] || !frustrum.Intersects(next.chunk.SectionBox(next.section)) {
+				continue
+			}
+			visited[key] = true
+			queue = append(queue, next)
+		}
+	}
+}
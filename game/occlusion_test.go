@@ -0,0 +1,125 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Returns a world of 256 blocks high without chunks.
+func newTestWorld() *World {
+	return newWorld(nil, nil, nil, 1, 0, 256, newWorldTime(0, false), nil, nil, nil)
+}
+
+// Adds a chunk filled with the block types returned by fill, computing the connections
+// of its sections like buffering them does.
+func addTestChunk(w *World, pos mgl32.Vec3, fill func(i, j, k int) string) *Chunk {
+	types := newBlockTypes(w.maxHeight - w.minHeight)
+	for i := range chunkWidth {
+		for j := range types[i] {
+			for k := range chunkWidth {
+				types[i][j][k] = fill(i, j, k)
+			}
+		}
+	}
+
+	c := newChunk(nil, nil, nil, pos, w.maxHeight-w.minHeight)
+	c.Init(types)
+	for _, s := range c.sections {
+		if s != nil {
+			s.computeConnections()
+			s.dirty = false
+		}
+	}
+	w.chunks.Set(pos, c)
+	return c
+}
+
+// Returns a fill placing a single block in a corner of the section s.
+func sectionCorner(s int, blockType string) func(i, j, k int) string {
+	return func(i, j, k int) string {
+		if i == 0 && j == s*chunkSectionSize && k == 0 {
+			return blockType
+		}
+		return ""
+	}
+}
+
+func TestWalkSectionsOccludedByWall(t *testing.T) {
+	const s = 8
+	solid := func(i, j, k int) string {
+		if j/chunkSectionSize == s {
+			return "stone"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name       string
+		wall       func(i, j, k int) string
+		behindSeen bool
+	}{
+		{"open", sectionCorner(s, "stone"), true},
+		{"wall", solid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+
+			// chunks in a row north of the camera, the middle one is the wall
+			camera := addTestChunk(w, mgl32.Vec3{0, 0, 0}, sectionCorner(s, "stone"))
+			wall := addTestChunk(w, mgl32.Vec3{0, 0, -chunkWidth}, tt.wall)
+			behind := addTestChunk(w, mgl32.Vec3{0, 0, -2 * chunkWidth}, sectionCorner(s, "stone"))
+
+			cam := newCamera(mgl32.Vec3{8, s*chunkSectionSize + 8, 8})
+			visible := w.MarkVisibleSections([]*Chunk{camera, wall, behind}, cam)
+
+			if !camera.sections[s].visible || !wall.sections[s].visible {
+				t.Fatal("sections in front of the camera are not visible")
+			}
+			if behind.sections[s].visible != tt.behindSeen {
+				t.Fatalf("section behind the wall visible = %v, want %v", behind.sections[s].visible, tt.behindSeen)
+			}
+			if want := 2; !tt.behindSeen && len(visible) != want {
+				t.Fatalf("%d visible chunks, want %d", len(visible), want)
+			}
+		})
+	}
+}
+
+// Reading blocks in sections without blocks used to create sections of inactive blocks
+// whose connections were never computed, hiding everything behind them.
+func TestBlockReadsDontCreateSections(t *testing.T) {
+	const s = 8
+	w := newTestWorld()
+	c := addTestChunk(w, mgl32.Vec3{0, 0, -chunkWidth}, sectionCorner(0, "stone"))
+	behind := addTestChunk(w, mgl32.Vec3{0, 0, -2 * chunkWidth}, sectionCorner(s, "stone"))
+	camera := addTestChunk(w, mgl32.Vec3{0, 0, 0}, sectionCorner(s, "stone"))
+
+	pos := mgl32.Vec3{8, s*chunkSectionSize + 8, -8}
+	if b := w.Block(pos); b != nil {
+		t.Fatalf("Block in an empty section = %v, want nil", b)
+	}
+	if b := w.LoadedBlock(pos); b != nil {
+		t.Fatalf("LoadedBlock in an empty section = %v, want nil", b)
+	}
+	if c.sections[s] != nil {
+		t.Fatal("reading a block created its section")
+	}
+
+	cam := newCamera(mgl32.Vec3{8, s*chunkSectionSize + 8, 8})
+	w.MarkVisibleSections([]*Chunk{camera, c, behind}, cam)
+	if !behind.sections[s].visible {
+		t.Fatal("section behind an empty section is not visible")
+	}
+
+	// a section created to change a block is open until it is buffered
+	if b := w.EditBlock(pos); b == nil || c.sections[s] == nil {
+		t.Fatal("EditBlock didn't create the section")
+	}
+	w.MarkVisibleSections([]*Chunk{camera, c, behind}, cam)
+	if !behind.sections[s].visible {
+		t.Fatal("section behind a new section is not visible")
+	}
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// OreVein configures the veins of one ore placed by the ore stage.
+type OreVein struct {
+	// ore block type
+	block string
+
+	// range of world heights where veins start
+	minHeight, maxHeight int
+
+	// max number of blocks in a vein
+	size int
+
+	// number of veins tried in each chunk
+	frequency int
+
+	// block types the ore can replace
+	hosts []string
+}
+
+// Veins placed by the ore stage, rarer ores are deeper and smaller.
+var oreVeins = []OreVein{
+	{block: "copper-ore", minHeight: 40, maxHeight: 110, size: 10, frequency: 8, hosts: []string{"stone"}},
+	{block: "iron-ore", minHeight: 6, maxHeight: 100, size: 8, frequency: 10, hosts: []string{"stone"}},
+	{block: "gold-ore", minHeight: 6, maxHeight: 40, size: 8, frequency: 3, hosts: []string{"stone"}},
+	{block: "redstone-ore", minHeight: 6, maxHeight: 20, size: 7, frequency: 5, hosts: []string{"stone"}},
+	{block: "lapis-ore", minHeight: 10, maxHeight: 40, size: 6, frequency: 2, hosts: []string{"stone"}},
+	{block: "diamond-ore", minHeight: 6, maxHeight: 16, size: 6, frequency: 1, hosts: []string{"stone"}},
+	{block: "emerald-ore", minHeight: 90, maxHeight: 200, size: 1, frequency: 6, hosts: []string{"stone"}},
+}
+
+// Places veins of ores in the terrain.
+// Veins are random walks from a start position planned like features,
+// so a vein crossing a chunk border continues in the neighbouring chunk.
+type OreFeature struct {
+	gen *WorldGenerator
+
+	// veins placed by the feature
+	veins []OreVein
+}
+
+func (f *OreFeature) Name() string {
+	return "ores"
+}
+
+// Veins start inside their chunk and walk at most size - 1 blocks away.
+func (f *OreFeature) Reach() int {
+	reach := 0
+	for _, vein := range f.veins {
+		reach = max(reach, (vein.size-1+chunkWidth-1)/chunkWidth)
+	}
+	return reach
+}
+
+// The random sequence of the plan is not used, each ore has its own sequence salted by its block type
+// so adding, removing or reordering ores doesn't move the others.
+func (f *OreFeature) Place(pos mgl32.Vec3, _ *rand.Rand, place func(FeatureBlock)) {
+	for _, vein := range f.veins {
+		rng := f.gen.ChunkRand(pos, featureSalt(vein.block))
+
+		for range vein.frequency {
+			x := int(pos.X()) + rng.Intn(chunkWidth)
+			y := vein.minHeight + rng.Intn(vein.maxHeight-vein.minHeight+1)
+			z := int(pos.Z()) + rng.Intn(chunkWidth)
+
+			for range vein.size {
+				if y < f.gen.minHeight || y >= f.gen.maxHeight {
+					break
+				}
+
+				place(FeatureBlock{pos: [3]int{x, y, z}, blockType: vein.block, hosts: vein.hosts})
+
+				// move to a random neighbour
+				d := directions[rng.Intn(len(directions))]
+				x, y, z = x+int(d.X()), y+int(d.Y()), z+int(d.Z())
+			}
+		}
+	}
+}
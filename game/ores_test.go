@@ -0,0 +1,192 @@
+package game
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const oreTestSeed = 42
+
+// Returns the positions of the chunk columns in a square of the given radius around the origin.
+func chunkSquare(radius int) []mgl32.Vec3 {
+	var positions []mgl32.Vec3
+	for x := -radius; x <= radius; x++ {
+		for z := -radius; z <= radius; z++ {
+			positions = append(positions, mgl32.Vec3{float32(x * chunkWidth), 0, float32(z * chunkWidth)})
+		}
+	}
+	return positions
+}
+
+// Returns the vein of an ore block type.
+func oreVein(blockType string) (OreVein, bool) {
+	for _, vein := range oreVeins {
+		if vein.block == blockType {
+			return vein, true
+		}
+	}
+	return OreVein{}, false
+}
+
+func TestOresReplaceHostsInRange(t *testing.T) {
+	before := []string{"heightmap", "surface", "caves", "deposits"}
+	withoutOres := newWorldGenerator(oreTestSeed, 0, 256, before, nil)
+	withOres := newWorldGenerator(oreTestSeed, 0, 256, append(slices.Clone(before), "ores"), nil)
+
+	found := map[string]int{}
+	for _, pos := range chunkSquare(3) {
+		terrain := withoutOres.Terrain(pos)
+		ores := withOres.Terrain(pos)
+
+		for x := range chunkWidth {
+			for y := range ores[x] {
+				for z := range chunkWidth {
+					if ores[x][y][z] == terrain[x][y][z] {
+						continue
+					}
+
+					vein, ok := oreVein(ores[x][y][z])
+					if !ok {
+						t.Fatalf("ores stage placed %q at %v", ores[x][y][z], [3]int{x, y, z})
+					}
+					if !slices.Contains(vein.hosts, terrain[x][y][z]) {
+						t.Fatalf("%s replaced %q at %v", vein.block, terrain[x][y][z], [3]int{x, y, z})
+					}
+
+					// veins start in the range and walk at most size - 1 blocks away
+					if y < vein.minHeight-(vein.size-1) || y > vein.maxHeight+(vein.size-1) {
+						t.Fatalf("%s at height %d, out of %d to %d", vein.block, y, vein.minHeight, vein.maxHeight)
+					}
+					found[vein.block]++
+				}
+			}
+		}
+	}
+
+	// ores below the mountains are in every terrain
+	for _, vein := range oreVeins {
+		if vein.maxHeight <= seaLevel+chunkWidth && found[vein.block] == 0 {
+			t.Errorf("no %s in %d chunks", vein.block, len(chunkSquare(3)))
+		}
+	}
+}
+
+func TestOreVeinSize(t *testing.T) {
+	gen := newWorldGenerator(oreTestSeed, 0, 256, nil, nil)
+	for _, vein := range oreVeins {
+		vein.frequency = 1
+		feature := &OreFeature{gen, []OreVein{vein}}
+
+		for _, pos := range chunkSquare(3) {
+			var blocks [][3]int
+			feature.Place(pos, nil, func(b FeatureBlock) {
+				blocks = append(blocks, b.pos)
+			})
+
+			if len(blocks) == 0 || len(blocks) > vein.size {
+				t.Fatalf("%s vein of %d blocks, want 1 to %d", vein.block, len(blocks), vein.size)
+			}
+
+			start := blocks[0]
+			if start[0] < int(pos.X()) || start[0] >= int(pos.X())+chunkWidth ||
+				start[2] < int(pos.Z()) || start[2] >= int(pos.Z())+chunkWidth ||
+				start[1] < vein.minHeight || start[1] > vein.maxHeight {
+				t.Fatalf("%s vein of chunk %v starts at %v", vein.block, pos, start)
+			}
+
+			// each block is next to the previous one
+			for i := 1; i < len(blocks); i++ {
+				d := 0
+				for j := range 3 {
+					d += max(blocks[i][j]-blocks[i-1][j], blocks[i-1][j]-blocks[i][j])
+				}
+				if d != 1 {
+					t.Fatalf("%s vein steps from %v to %v", vein.block, blocks[i-1], blocks[i])
+				}
+			}
+		}
+	}
+}
+
+func TestOreVeinsCrossChunkBorders(t *testing.T) {
+	gen := newWorldGenerator(oreTestSeed, 0, 256, nil, nil)
+	feature := &OreFeature{gen, oreVeins}
+	reach := feature.Reach()
+
+	crossing := 0
+	for _, pos := range chunkSquare(3) {
+		for target := range gen.Plan(feature, pos).blocks {
+			dx := int(target.X()-pos.X()) / chunkWidth
+			dz := int(target.Z()-pos.Z()) / chunkWidth
+			if max(dx, -dx) > reach || max(dz, -dz) > reach {
+				t.Fatalf("vein of chunk %v reaches chunk %v past the reach %d", pos, target, reach)
+			}
+			if target != pos {
+				crossing++
+			}
+		}
+	}
+	if crossing == 0 {
+		t.Fatal("no vein crosses a chunk border")
+	}
+}
+
+func TestOresDeterministic(t *testing.T) {
+	stages := []string{"heightmap", "surface", "caves", "deposits", "ores"}
+	positions := chunkSquare(1)
+
+	first := newWorldGenerator(oreTestSeed, 0, 256, stages, nil)
+	want := map[mgl32.Vec3]BlockTypes{}
+	for _, pos := range positions {
+		want[pos] = first.Terrain(pos)
+	}
+
+	// a fresh generator in another order gives the same blocks
+	second := newWorldGenerator(oreTestSeed, 0, 256, stages, nil)
+	rand.New(rand.NewSource(1)).Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
+	for _, pos := range positions {
+		got := second.Terrain(pos)
+		for x := range chunkWidth {
+			for y := range got[x] {
+				if !slices.Equal(got[x][y][:], want[pos][x][y][:]) {
+					t.Fatalf("chunk %v differs at x %d y %d", pos, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestOreVeinsIndependentOfOrder(t *testing.T) {
+	gen := newWorldGenerator(oreTestSeed, 0, 256, nil, nil)
+	place := func(veins []OreVein, pos mgl32.Vec3) map[[3]int]string {
+		blocks := map[[3]int]string{}
+		(&OreFeature{gen, veins}).Place(pos, nil, func(b FeatureBlock) {
+			blocks[b.pos] = b.blockType
+		})
+		return blocks
+	}
+
+	// without the first ore and in reverse order the other veins don't move
+	others := slices.Clone(oreVeins[1:])
+	slices.Reverse(others)
+	for _, pos := range chunkSquare(1) {
+		want := place(oreVeins, pos)
+		for p, blockType := range want {
+			if blockType == oreVeins[0].block {
+				delete(want, p)
+			}
+		}
+
+		got := place(others, pos)
+		for p, blockType := range want {
+			if got[p] != blockType {
+				t.Fatalf("chunk %v has %q at %v, want %q", pos, got[p], p, blockType)
+			}
+		}
+	}
+}
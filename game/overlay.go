@@ -0,0 +1,84 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// DebugOverlay shows details about the game state in the top left corner of the screen.
+type DebugOverlay struct {
+	text *TextRenderer
+
+	// if the overlay is currently shown
+	visible bool
+
+	// frames rendered since the fps was last measured
+	frames     int
+	fps        int
+	fpsUpdated time.Time
+}
+
+const (
+	// layout in screen pixels
+	debugOverlayMargin   = 10
+	debugOverlayTextSize = 16
+
+	// how often the fps is measured
+	fpsInterval = time.Second
+)
+
+func newDebugOverlay(text *TextRenderer) *DebugOverlay {
+	o := &DebugOverlay{
+		text:       text,
+		fpsUpdated: time.Now(),
+	}
+	return o
+}
+
+// Toggles the overlay.
+func (o *DebugOverlay) Toggle() {
+	o.visible = !o.visible
+}
+
+// Counts a rendered frame and measures the fps once per interval.
+// Should be called once per frame even when the overlay is hidden.
+func (o *DebugOverlay) Frame() {
+	o.frames++
+	elapsed := time.Since(o.fpsUpdated)
+	if elapsed >= fpsInterval {
+		o.fps = int(float64(o.frames) / elapsed.Seconds())
+		o.frames = 0
+		o.fpsUpdated = time.Now()
+	}
+}
+
+// Adds the overlay text to the text renderer if it is visible.
+// Takes the number of chunks drawn this frame.
+func (o *DebugOverlay) Add(player *Player, world *World, drawnChunks int) {
+	if !o.visible {
+		return
+	}
+
+	pos := player.body.position
+	chunk, i, j, k := world.Position(pos)
+	climate := world.generator.Climate(pos.X(), pos.Z())
+	loader := world.loader.Metrics()
+	sky, block := world.Light(int(math.Floor(float64(pos.X()))), int(math.Floor(float64(pos.Y()))), int(math.Floor(float64(pos.Z()))))
+
+	lines := fmt.Sprintf("%d fps\n", o.fps) +
+		fmt.Sprintf("xyz: %.2f / %.2f / %.2f\n", pos.X(), pos.Y(), pos.Z()) +
+		fmt.Sprintf("chunk: %d %d %d in %d %d %d\n", i, j, k, int(chunk.X())/chunkWidth, floorDiv(j, chunkSectionSize), int(chunk.Z())/chunkWidth) +
+		fmt.Sprintf("biome: %s\n", selectBiome(climate)) +
+		fmt.Sprintf("climate: t %.2f h %.2f c %.2f\n", climate.temperature, climate.humidity, climate.continentalness) +
+		fmt.Sprintf("facing: %s\n", newHorizontalDirection(player.camera.view)) +
+		fmt.Sprintf("light: %d sky, %d block\n", sky, block) +
+		fmt.Sprintf("time: %d, day %d\n", world.time.Ticks(), world.time.ticks/dayLength) +
+		fmt.Sprintf("chunks: %d loaded, %d drawn\n", world.chunks.Len(), drawnChunks) +
+		fmt.Sprintf("loader: %d pending (peak %d), %d loaded, %d dropped", loader.pending, loader.peak, loader.loaded, loader.dropped)
+
+	o.text.Add("minecraft", mgl32.Vec2{debugOverlayMargin, debugOverlayMargin}, debugOverlayTextSize, alignLeft, textYellow)
+	o.text.Add(lines, mgl32.Vec2{debugOverlayMargin, debugOverlayMargin + debugOverlayTextSize*textLineSpacing}, debugOverlayTextSize, alignLeft, textWhite)
+}
@@ -16,7 +16,8 @@ type PhysicsEngine struct {
 	// world functions to get a block and surroundings based on position
 	discover             func(mgl32.Vec3) Box      // the aabb at a point
 	discoverSurroundings func(...mgl32.Vec3) []Box // the surrounding aabb
-	discoverActive       func(mgl32.Vec3) *Box     // same as discover but returns null if not active
+	discoverActive       func(mgl32.Vec3) []Box    // the boxes of the solid block at a point, none if not solid
+	discoverFluid        func(mgl32.Vec3) *Fluid   // the fluid at a point, null if none
 }
 
 const (
@@ -27,18 +28,23 @@ const (
 	groundFrictionCoef        = 1
 	wallImpulseRestitution    = 0.3
 	flyingSpeedMultipier      = 4.0
+	swimSpeed                 = 3
+	swimSpeedMultiplier       = 0.5
+	stepHeight                = 0.5
 )
 
 func newPhysicsEngine(
 	discover func(mgl32.Vec3) Box,
 	discoverSurroundings func(...mgl32.Vec3) []Box,
-	discoverActive func(mgl32.Vec3) *Box,
+	discoverActive func(mgl32.Vec3) []Box,
+	discoverFluid func(mgl32.Vec3) *Fluid,
 ) *PhysicsEngine {
 	return &PhysicsEngine{
 		bodies:               make(map[*RigidBody]bool),
 		discover:             discover,
 		discoverSurroundings: discoverSurroundings,
 		discoverActive:       discoverActive,
+		discoverFluid:        discoverFluid,
 	}
 }
 
@@ -94,6 +100,14 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 		body.force = body.force.Add(mgl32.Vec3{0, body.mass * -gravity, 0})
 	}
 
+	// bodies with their center in a fluid float and are slowed down
+	body.fluid = p.discoverFluid(body.position.Sub(mgl32.Vec3{0, body.height / 2, 0}))
+	if body.fluid != nil && !body.flying {
+		buoyancy := mgl32.Vec3{0, body.mass * gravity * body.fluid.buoyancy, 0}
+		drag := body.velocity.Mul(-body.mass * body.fluid.drag)
+		body.force = body.force.Add(buoyancy).Add(drag)
+	}
+
 	// compute and set acceleration and velocity from the net force
 	acc := body.force.Mul(1 / body.mass)
 	body.velocity = body.velocity.Add(acc.Mul(float32(delta)))
@@ -125,7 +139,7 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 			length:    movementLength,
 		}
 
-		march := ray.March(func(point mgl32.Vec3) *Box {
+		march := ray.March(func(point mgl32.Vec3) []Box {
 			return p.discoverActive(point)
 		})
 
@@ -146,18 +160,20 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 		// determine what type of collider based on heuristics
 		// TODO: dont use heuristics to determine collider type
 		// instead just use aabb on all 3 dimensions
+		// colliders smaller than a block (e.g. slabs) are placed by the cell they are in
+		cell := cellBox(collider.center)
 		ground := false
 		ceiling := false
 		wall := false
 		for _, worldPosition := range body.worldBlocks {
-			if collider.center.X() == worldPosition.center.X() && collider.center.Z() == worldPosition.center.Z() {
-				if collider.center.Y() < worldPosition.center.Y() {
+			if cell.center.X() == worldPosition.center.X() && cell.center.Z() == worldPosition.center.Z() {
+				if cell.center.Y() < worldPosition.center.Y() {
 					ground = true
 				} else {
 					ceiling = true
 				}
 				break
-			} else if worldPosition.center.Y() == collider.center.Y() {
+			} else if worldPosition.center.Y() == cell.center.Y() {
 				wall = true
 				break
 			}
@@ -166,8 +182,9 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 		// if this is a ground or ceiling collider
 		if ground || ceiling {
 			if ground {
+				// the highest ground collider holds the body, e.g. the step of stairs
 				b, depth := collider.Intersection(body.shape, 1)
-				if b {
+				if b && (groundedDepth == nil || depth > *groundedDepth) {
 					groundedDepth = &depth
 				}
 			} else {
@@ -178,8 +195,16 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 				}
 			}
 		} else if wall { // if this is a wall collider
+			// walls below the body are passed over and low walls are stepped on when walking
+			step := collider.max.Y() - body.shape.min.Y()
+			if step <= 0 {
+				continue
+			}
+
 			b, pen, face := body.shape.IntersectionXZ(collider)
-			if b {
+			if b && step <= stepHeight && body.grounded {
+				body.setPosition(body.position.Add(mgl32.Vec3{0, step, 0}))
+			} else if b {
 				if !body.staticImpulsesDisabled {
 					p.applyStaticImpulse(body, face.Normal(), float32(delta), wallImpulseRestitution)
 				}
@@ -211,6 +236,10 @@ func (p *PhysicsEngine) update(body *RigidBody, delta float64) {
 	// resolve collisions with other registered bodies
 	// TODO: optimize
 	for otherBody := range p.bodies {
+		if body.bodyCollisionsDisabled || otherBody.bodyCollisionsDisabled {
+			continue
+		}
+
 		isSameY := false
 		for _, worldBlock := range otherBody.worldBlocks {
 			_, exists := myWorldBlocks[worldBlock.center.Y()]
@@ -308,10 +337,12 @@ type RigidBody struct {
 
 	// special states (set by the simulation)
 	grounded bool
+	fluid    *Fluid // fluid the body is in, nil if none
 
 	// toggles
 	flying                 bool
 	staticImpulsesDisabled bool // useful for player movement
+	bodyCollisionsDisabled bool // passes through other bodies, useful for items
 }
 
 // Converts movement vector into a velocity change for player-like movement.
@@ -335,6 +366,11 @@ func (r *RigidBody) Jump() {
 	r.grounded = false
 }
 
+// Swims up by setting the vertical velocity, used while in a fluid.
+func (r *RigidBody) Swim() {
+	r.velocity[1] = swimSpeed
+}
+
 // Sets position and sets a new shape.
 func (r *RigidBody) setPosition(p mgl32.Vec3) {
 	r.position = p
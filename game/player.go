@@ -27,7 +27,6 @@ const (
 	playerSpeed  = 6.5
 
 	// surroundings
-	playerRadius               = 20
 	cameraCycloidCancelEpsilon = 0.1
 )
 
@@ -92,18 +91,8 @@ func (p *Player) Move(forward, right float32, fly bool) {
 	}
 
 	movement = movement.Mul(playerSpeed)
-	p.body.Move(movement, fly)
-}
-
-// Returns true if player sees the chunk.
-// TODO: convert this to full frustrum cull
-func (p *Player) Sees(chunk *Chunk) bool {
-	frustrum := p.camera.Frustrum()
-	box := chunk.Box()
-	for _, corner := range box.Corners() {
-		if frustrum.near.Distance(corner) >= 0 {
-			return true
-		}
+	if p.body.fluid != nil {
+		movement = movement.Mul(swimSpeedMultiplier)
 	}
-	return box.Distance(p.camera.pos) < playerRadius
+	p.body.Move(movement, fly)
 }
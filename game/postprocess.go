@@ -0,0 +1,292 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// PostProcessor renders the world offscreen in high dynamic range and applies a chain of effects
+// before it reaches the screen: bloom around emissive blocks, an underwater tint, tonemapping,
+// gamma and FXAA. Each effect can be toggled, the passes of disabled effects are skipped.
+type PostProcessor struct {
+	// shaders of the composite, blur and anti-aliasing passes
+	compositeShader, blurShader, fxaaShader *Shader
+
+	// world target: scene color and light of the emissive blocks, with a depth buffer
+	hdrFbo, sceneTexture, brightTexture, depthRbo uint32
+
+	// half resolution targets the bloom is blurred back and forth between
+	bloomFbos, bloomTextures [2]uint32
+
+	// target of the composite pass read by the anti-aliasing pass
+	ldrFbo, ldrTexture uint32
+
+	// quad covering the screen, a vao per shader
+	vbo                            uint32
+	compositeVao, blurVao, fxaaVao uint32
+
+	// size of the framebuffer in pixels
+	width, height int32
+
+	// enabled effects by name
+	effects map[string]bool
+
+	// if the camera is under water, the view is tinted when the underwater effect is enabled
+	underwater bool
+
+	// framebuffer the result is drawn to, 0 for the screen
+	output uint32
+}
+
+// Effects of the post processor, in the order they are applied.
+var postEffects = []string{"bloom", "underwater", "tonemap", "gamma", "fxaa"}
+
+const (
+	// blur passes of the bloom, alternating horizontal and vertical
+	bloomBlurPasses = 6
+
+	// strength of the bloom added to the scene
+	bloomStrength = 0.8
+
+	// brightness of the scene before it is tonemapped
+	tonemapExposure = 1.0
+
+	// gamma of the display
+	displayGamma = 2.2
+)
+
+func newPostProcessor(compositeShader, blurShader, fxaaShader *Shader) *PostProcessor {
+	p := &PostProcessor{
+		compositeShader: compositeShader,
+		blurShader:      blurShader,
+		fxaaShader:      fxaaShader,
+		effects:         make(map[string]bool),
+	}
+	for _, e := range postEffects {
+		p.effects[e] = true
+	}
+	return p
+}
+
+// Initializes the render targets of the size of the framebuffer and the screen quad on the GPU.
+func (p *PostProcessor) Init(width, height int32) {
+	p.width, p.height = width, height
+
+	// world target, the emissive light goes to a second attachment
+	p.sceneTexture = p.createTexture(p.width, p.height, gl.RGBA16F, gl.FLOAT)
+	p.brightTexture = p.createTexture(p.width, p.height, gl.RGBA16F, gl.FLOAT)
+	gl.GenRenderbuffers(1, &p.depthRbo)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, p.depthRbo)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, p.width, p.height)
+
+	gl.GenFramebuffers(1, &p.hdrFbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.hdrFbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, p.sceneTexture, 0)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT1, gl.TEXTURE_2D, p.brightTexture, 0)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, p.depthRbo)
+	attachments := []uint32{gl.COLOR_ATTACHMENT0, gl.COLOR_ATTACHMENT1}
+	gl.DrawBuffers(int32(len(attachments)), &attachments[0])
+	p.checkFramebuffer("hdr")
+
+	// bloom targets
+	for i := range p.bloomFbos {
+		p.bloomTextures[i] = p.createTexture(p.width/2, p.height/2, gl.RGBA16F, gl.FLOAT)
+		gl.GenFramebuffers(1, &p.bloomFbos[i])
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.bloomFbos[i])
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, p.bloomTextures[i], 0)
+		p.checkFramebuffer("bloom")
+	}
+
+	// composite target
+	p.ldrTexture = p.createTexture(p.width, p.height, gl.RGBA8, gl.UNSIGNED_BYTE)
+	gl.GenFramebuffers(1, &p.ldrFbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.ldrFbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, p.ldrTexture, 0)
+	p.checkFramebuffer("ldr")
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	// screen quad
+	buffer := []float32{}
+	for _, v := range newQuad(0, 1, 0, 1) {
+		buffer = append(buffer, v.pos.X(), v.pos.Y())
+	}
+	gl.GenBuffers(1, &p.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(buffer)*4, gl.Ptr(buffer), gl.STATIC_DRAW)
+	p.compositeVao = p.createQuadVao(p.compositeShader)
+	p.blurVao = p.createQuadVao(p.blurShader)
+	p.fxaaVao = p.createQuadVao(p.fxaaShader)
+}
+
+// Creates a texture of the size sampled linearly and clamped to its edges.
+func (p *PostProcessor) createTexture(width, height int32, format int32, xtype uint32) uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, format, width, height, 0, gl.RGBA, xtype, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	return texture
+}
+
+// Creates a vao reading the screen quad for the shader.
+func (p *PostProcessor) createQuadVao(shader *Shader) uint32 {
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	vertAttrib := uint32(gl.GetAttribLocation(shader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 2, gl.FLOAT, false, 2*4, 0)
+	return vao
+}
+
+// Panics if the bound framebuffer can't be rendered to.
+func (p *PostProcessor) checkFramebuffer(name string) {
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		log.Panicf("incomplete %s framebuffer: 0x%x", name, status)
+	}
+}
+
+// Prepares the rendering of the world to the high dynamic range target.
+// Should be called before the world is drawn, the screen overlays are drawn after Resolve.
+func (p *PostProcessor) Prepare() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.hdrFbo)
+	gl.Viewport(0, 0, p.width, p.height)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// only the emissive blocks write light for the bloom
+	black := []float32{0, 0, 0, 0}
+	gl.ClearBufferfv(gl.COLOR, 1, &black[0])
+}
+
+// Applies the enabled effects to the world and draws it to the output.
+// Leaves the output bound with a cleared depth buffer for the overlays.
+func (p *PostProcessor) Resolve() {
+	gl.Disable(gl.DEPTH_TEST)
+
+	bloom := p.effects["bloom"]
+	if bloom {
+		p.blurBloom()
+	}
+
+	// composite pass, to the screen unless anti-aliased after
+	fxaa := p.effects["fxaa"]
+	if fxaa {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.ldrFbo)
+	} else {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.output)
+	}
+	gl.Viewport(0, 0, p.width, p.height)
+
+	shader := p.compositeShader.handle
+	gl.UseProgram(shader)
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("scene\x00")), 0)
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("bloom\x00")), 1)
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("bloomEnabled\x00")), boolUniform(bloom))
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("tonemapEnabled\x00")), boolUniform(p.effects["tonemap"]))
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("gammaEnabled\x00")), boolUniform(p.effects["gamma"]))
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("underwater\x00")), boolUniform(p.effects["underwater"] && p.underwater))
+	gl.Uniform1f(gl.GetUniformLocation(shader, gl.Str("bloomStrength\x00")), bloomStrength)
+	gl.Uniform1f(gl.GetUniformLocation(shader, gl.Str("exposure\x00")), tonemapExposure)
+	gl.Uniform1f(gl.GetUniformLocation(shader, gl.Str("gamma\x00")), displayGamma)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, p.sceneTexture)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, p.bloomTextures[0])
+	gl.BindVertexArray(p.compositeVao)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(quadIndices)))
+
+	// anti-aliasing pass
+	if fxaa {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.output)
+		gl.UseProgram(p.fxaaShader.handle)
+		gl.Uniform1i(gl.GetUniformLocation(p.fxaaShader.handle, gl.Str("image\x00")), 0)
+		gl.ActiveTexture(gl.TEXTURE0)
+		gl.BindTexture(gl.TEXTURE_2D, p.ldrTexture)
+		gl.BindVertexArray(p.fxaaVao)
+		gl.DrawArrays(gl.TRIANGLES, 0, int32(len(quadIndices)))
+	}
+
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// Blurs the light of the emissive blocks at half resolution, the result ends in the first bloom texture.
+func (p *PostProcessor) blurBloom() {
+	shader := p.blurShader.handle
+	gl.UseProgram(shader)
+	gl.Uniform1i(gl.GetUniformLocation(shader, gl.Str("image\x00")), 0)
+	horizontalUniform := gl.GetUniformLocation(shader, gl.Str("horizontal\x00"))
+
+	gl.Viewport(0, 0, p.width/2, p.height/2)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(p.blurVao)
+
+	// the first pass reads the full resolution light, then the passes read the other bloom texture
+	source := p.brightTexture
+	for i := range bloomBlurPasses {
+		target := (i + 1) % 2
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.bloomFbos[target])
+		gl.Uniform1i(horizontalUniform, boolUniform(i%2 == 0))
+		gl.BindTexture(gl.TEXTURE_2D, source)
+		gl.DrawArrays(gl.TRIANGLES, 0, int32(len(quadIndices)))
+		source = p.bloomTextures[target]
+	}
+}
+
+// Enables or disables an effect.
+func (p *PostProcessor) SetEffect(name string, enabled bool) error {
+	if _, ok := p.effects[name]; !ok {
+		return fmt.Errorf("unknown effect %q", name)
+	}
+	p.effects[name] = enabled
+	return nil
+}
+
+// Returns the GL value of a bool uniform.
+func boolUniform(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Returns the console command listing and toggling the post processing effects.
+func postCommand(p *PostProcessor) *Command {
+	return &Command{
+		usage: "post <" + strings.Join(postEffects, "|") + "> on|off, post list",
+		run: func(args []string) (string, error) {
+			if len(args) == 1 && args[0] == "list" {
+				effects := make([]string, 0, len(postEffects))
+				for _, e := range postEffects {
+					state := "off"
+					if p.effects[e] {
+						state = "on"
+					}
+					effects = append(effects, e+" "+state)
+				}
+				return strings.Join(effects, ", "), nil
+			}
+
+			if len(args) != 2 {
+				return "", fmt.Errorf("missing effect or state")
+			}
+			if !slices.Contains([]string{"on", "off"}, args[1]) {
+				return "", fmt.Errorf("invalid state %q", args[1])
+			}
+			if err := p.SetEffect(args[0], args[1] == "on"); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s", args[0], args[1]), nil
+		},
+	}
+}
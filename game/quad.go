@@ -12,6 +12,12 @@ type Vertex struct {
 // A quad is 2 triangles (6 vertices).
 type Quad [6]Vertex
 
+// Indices of the quad vertices split along the bottom-right to top-left diagonal, as they are made.
+var quadIndices = [6]int{0, 1, 2, 3, 4, 5}
+
+// Indices of the quad vertices split along the other diagonal, bottom-left to top-right.
+var flippedQuadIndices = [6]int{0, 1, 4, 0, 4, 2}
+
 // Makes a default quad cenetered at origin in the XY plane with size 2.
 func newQuad(umin, umax, vmin, vmax float32) Quad {
 	quad := [6]Vertex{
@@ -60,3 +66,32 @@ func (q Quad) TranlateDirection(dir Direction) Quad {
 
 	return quad
 }
+
+// Turns the texture of the quad a quarter, the quad must show the whole atlas texture with the coords.
+func (q Quad) RotateTexture(umin, umax, vmin, vmax float32) Quad {
+	quad := Quad(q)
+	for i := range quad {
+		s := (quad[i].tex.X() - umin) / (umax - umin)
+		t := (quad[i].tex.Y() - vmin) / (vmax - vmin)
+		quad[i].tex = mgl32.Vec2{lerp(t, umin, umax), lerp(1-s, vmin, vmax)}
+	}
+	return quad
+}
+
+// Appends the vertices (position and texture coords) of a quad showing an atlas texture on the screen.
+// The quad is centered at pos and scaled before the projection, depth orders overlapping quads.
+func appendScreenQuad(buffer []float32, atlas *TextureAtlas, projection mgl32.Mat4, tex [2]int, pos mgl32.Vec2, scale, depth float32) []float32 {
+	umin, umax, vmin, vmax := atlas.Coords(tex[0], tex[1])
+	quad := newQuad(umin, umax, vmin, vmax)
+
+	m := mgl32.Translate3D(pos.X(), pos.Y(), 0).Mul4(mgl32.Scale3D(scale, scale, 1))
+	m = projection.Mul4(m)
+	for _, v := range quad {
+		vert := m.Mul4x1(v.pos.Vec2().Vec4(0, 1))
+		buffer = append(buffer,
+			vert.X(), vert.Y(), depth,
+			v.tex.X(), v.tex.Y(),
+		)
+	}
+	return buffer
+}
@@ -24,8 +24,9 @@ type March struct {
 }
 
 // March marches in the direction of the ray, detection the first the block in sight,
-// where the callback is used to determine if a block is present.
-func (r Ray) March(find func(p mgl32.Vec3) *Box) March {
+// where the callback returns the boxes of the block at a position, none if no block is present.
+// Blocks are hit where the ray enters their nearest box, the ray passes by blocks that don't fill their cell.
+func (r Ray) March(find func(p mgl32.Vec3) []Box) March {
 	// helper to find the smallest `t` such that `s + (ds * t)` is an integer
 	// i.e finds the next block point
 	intbound := func(s, ds mgl32.Vec3) mgl32.Vec3 {
@@ -67,17 +68,30 @@ func (r Ray) March(find func(p mgl32.Vec3) *Box) March {
 	var face Direction
 	var hit float32
 	for {
-		b := find(p)
-		if b != nil {
-			hitPos := r.origin.Add(r.direction.Mul(hit))
-			return March{
+		var nearest March
+		var nearestT float32
+		for _, b := range find(p) {
+			t, boxFace, ok := r.enter(b, radius)
+			if !ok || nearest.hit && t >= nearestT {
+				continue
+			}
+
+			// the ray starts inside the box
+			if t < 0 {
+				t, boxFace = hit, face
+			}
+			nearestT = t
+			nearest = March{
 				hit:      true,
 				blockPos: p,
-				hitPos:   hitPos,
-				face:     face,
-				box:      *b,
+				hitPos:   r.origin.Add(r.direction.Mul(t)),
+				face:     boxFace,
+				box:      b,
 			}
 		}
+		if nearest.hit {
+			return nearest
+		}
 
 		if tmax.X() < tmax.Y() {
 			if tmax.X() < tmax.Z() {
@@ -126,3 +140,35 @@ func (r Ray) March(find func(p mgl32.Vec3) *Box) March {
 		hit: false,
 	}
 }
+
+// Returns where the ray enters the box in multiples of its direction and the face it enters by.
+// Negative if the ray starts inside the box, false if the ray misses the box within the radius.
+func (r Ray) enter(b Box, radius float32) (float32, Direction, bool) {
+	tNear, tFar := float32(math.Inf(-1)), float32(math.Inf(1))
+	face := noDirection
+	for axis := range 3 {
+		o, d := r.origin[axis], r.direction[axis]
+		if d == 0 {
+			if o < b.min[axis] || o > b.max[axis] {
+				return 0, noDirection, false
+			}
+			continue
+		}
+
+		t1, t2 := (b.min[axis]-o)/d, (b.max[axis]-o)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tNear {
+			var n mgl32.Vec3
+			n[axis] = -sign(d)
+			tNear, face = t1, newDirection(n)
+		}
+		tFar = min(tFar, t2)
+	}
+
+	if tNear > tFar || tFar < 0 || tNear > radius {
+		return 0, noDirection, false
+	}
+	return tNear, face, true
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// directory screenshots are saved to
+const screenshotDir = "./screenshots"
+
+// CaptureOptions describes a render of a world from a camera pose without playing it.
+type CaptureOptions struct {
+	// name of the world to render
+	World string
+
+	// position of the camera and the direction it looks at
+	Pos, Dir [3]float32
+
+	// time of day in ticks after sunrise, the time of the world if negative
+	Time int64
+
+	// path of the PNG file written
+	Output string
+}
+
+// Saves the frame drawn to the screen as a PNG in the screenshots directory.
+// Should be called after the frame is drawn and before it is swapped.
+func (g *Game) TakeScreenshot() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	img := readPixels(g.post.width, g.post.height)
+
+	path := filepath.Join(screenshotDir, time.Now().Format("2006-01-02_15.04.05.000")+".png")
+	if err := savePNG(img, path); err != nil {
+		g.console.show(fmt.Sprintf("failed to save screenshot: %v", err))
+		return
+	}
+	g.console.show("saved screenshot " + path)
+}
+
+// Renders the world from the camera pose to a PNG without playing it.
+// The frame is drawn in a headless context, so no display server is needed and software drivers like llvmpipe work,
+// or in a hidden window where there is no headless context.
+// Chunks are shown without fading in so renders are repeatable.
+func Capture(opts CaptureOptions) {
+	if mgl32.Vec3(opts.Dir).Len() == 0 {
+		log.Fatal("capture direction is zero")
+	}
+
+	db := newDatabase("./db")
+	db.Migrate()
+
+	var worldEntity *WorldEntity
+	for _, w := range db.Worlds() {
+		if strings.TrimSpace(w.name) == opts.World {
+			worldEntity = w
+		}
+	}
+	if worldEntity == nil {
+		log.Fatalf("world %q not found", opts.World)
+	}
+
+	context, err := newHeadlessContext()
+	if err != nil {
+		log.Println("No headless context, capturing in a hidden window:", err)
+		window := newWindow(false)
+		defer window.Terminate()
+	} else {
+		defer context.Terminate()
+	}
+
+	img := renderCapture(db, worldEntity, opts)
+	if err := savePNG(img, opts.Output); err != nil {
+		log.Fatalf("failed to save capture: %v", err)
+	}
+	log.Println("Saved capture", opts.Output)
+}
+
+// Returns the world rendered from the camera pose with the current context.
+// The frame is drawn to an offscreen framebuffer of the size of a window, so it doesn't depend on the screen.
+func renderCapture(db *Database, worldEntity *WorldEntity, opts CaptureOptions) *image.RGBA {
+	g := &Game{db: db}
+	g.Load(worldEntity)
+
+	// pose the camera, the player body stays where the chunks are spawned around
+	pos := mgl32.Vec3(opts.Pos)
+	g.player.body.position = pos
+	g.player.camera.pos = pos
+	g.player.camera.view = mgl32.Vec3(opts.Dir).Normalize()
+	if opts.Time >= 0 {
+		g.world.time.Set(opts.Time)
+	}
+	g.light.Move(pos, g.world.time)
+
+	g.world.SpawnSurroundings(pos, nil)
+	g.world.DrainSpawnQueue()
+	for _, c := range g.world.chunks.All() {
+		c.spawned = time.Time{}
+	}
+
+	// offscreen target of the size of the post processing targets
+	var fbo uint32
+	texture := g.post.createTexture(g.post.width, g.post.height, gl.RGBA8, gl.UNSIGNED_BYTE)
+	gl.GenFramebuffers(1, &fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+	g.post.checkFramebuffer("capture")
+
+	g.post.output = fbo
+	g.DrawWorld()
+	gl.Finish()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	return readPixels(g.post.width, g.post.height)
+}
+
+// Returns the pixels of the bound framebuffer, opaque and with the top row first.
+func readPixels(width, height int32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+
+	// the rows of the framebuffer start at the bottom
+	stride := img.Stride
+	row := make([]byte, stride)
+	for y := range int(height) / 2 {
+		top := img.Pix[y*stride : (y+1)*stride]
+		bottom := img.Pix[(int(height)-1-y)*stride : (int(height)-y)*stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+
+	// the alpha left by blending isn't meant to be seen
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// Writes the image as a PNG, creating its directory.
+func savePNG(img image.Image, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden images of the captures")
+
+const (
+	// golden images are stored downsampled by this factor to keep them small
+	goldenScale = 4
+
+	// largest difference of a channel of a pixel still considered equal
+	goldenChannelTolerance = 8
+
+	// largest fraction of pixels allowed to differ
+	goldenPixelTolerance = 0.005
+)
+
+// Returns the image scaled down by the factor, each pixel the average of a square of pixels.
+func downsample(img *image.RGBA, factor int) *image.RGBA {
+	size := img.Bounds().Size().Div(factor)
+	out := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := range size.Y {
+		for x := range size.X {
+			var sum [4]int
+			for dy := range factor {
+				for dx := range factor {
+					i := img.PixOffset(x*factor+dx, y*factor+dy)
+					for c := range sum {
+						sum[c] += int(img.Pix[i+c])
+					}
+				}
+			}
+			i := out.PixOffset(x, y)
+			for c := range sum {
+				out.Pix[i+c] = uint8(sum[c] / (factor * factor))
+			}
+		}
+	}
+	return out
+}
+
+// Returns the number of pixels with a channel differing by more than the tolerance.
+func diffPixels(a, b *image.RGBA) int {
+	diff := 0
+	for i := 0; i < len(a.Pix); i += 4 {
+		for c := range 3 {
+			d := int(a.Pix[i+c]) - int(b.Pix[i+c])
+			if max(d, -d) > goldenChannelTolerance {
+				diff++
+				break
+			}
+		}
+	}
+	return diff
+}
+
+// Returns the PNG at the path as RGBA.
+func loadPNG(t *testing.T, path string) *image.RGBA {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("missing golden image, run the test with -update: %v", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+	return rgba
+}
+
+func TestCaptureGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generating and drawing the world is slow")
+	}
+
+	// the golden images are rendered by llvmpipe, hardware drivers differ by more than the tolerance
+	t.Setenv("LIBGL_ALWAYS_SOFTWARE", "1")
+	context, err := newHeadlessContext()
+	if err != nil {
+		t.Skipf("no OpenGL context: %v", err)
+	}
+	defer context.Terminate()
+	if renderer := gl.GoStr(gl.GetString(gl.RENDERER)); !strings.HasPrefix(renderer, "llvmpipe") {
+		t.Skipf("golden images are rendered by llvmpipe, not %s", renderer)
+	}
+
+	golden, err := filepath.Abs(filepath.Join("testdata", "capture.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newDatabase(filepath.Join(t.TempDir(), "db"))
+	db.Migrate()
+	world := db.World(db.CreateWorld("golden"))
+
+	// the shaders and assets are loaded relative to the root of the repository
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	got := downsample(renderCapture(db, world, CaptureOptions{
+		Pos:  [3]float32{100.5, 125.5, 100.5},
+		Dir:  [3]float32{1, -0.3, 0.5},
+		Time: 2000,
+	}), goldenScale)
+	if *updateGolden {
+		if err := savePNG(got, golden); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want := loadPNG(t, golden)
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("capture of size %v, golden image of size %v", got.Bounds(), want.Bounds())
+	}
+	if diff := diffPixels(got, want); float64(diff) > goldenPixelTolerance*float64(len(got.Pix)/4) {
+		failed := filepath.Join(os.TempDir(), "capture_failed.png")
+		savePNG(got, failed)
+		t.Fatalf("%d pixels differ from the golden image, capture saved to %s", diff, failed)
+	}
+}
@@ -0,0 +1,247 @@
+package game
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// BlockShape is the geometry of a block type, used to mesh the block and for its collision and selection boxes.
+// Shapes are modeled facing north and right side up, placed blocks are rotated to their facing
+// and flipped upside down when on top (see the facing and top state properties).
+type BlockShape struct {
+	// boxes of the shape in a block centered at the origin
+	boxes []Box
+
+	// meshed as two quads crossing along the diagonals of the block instead of its boxes (e.g. flowers)
+	cross bool
+
+	// if bodies collide with the boxes, otherwise they only select the block
+	collides bool
+
+	// light levels absorbed by the block when it isn't a full cube
+	lightOpacity int
+}
+
+var (
+	cubeShape = &BlockShape{
+		boxes:    []Box{newBox(mgl32.Vec3{-0.5, -0.5, -0.5}, mgl32.Vec3{0.5, 0.5, 0.5})},
+		collides: true,
+	}
+
+	// bottom half of the block
+	slabShape = &BlockShape{
+		boxes:        []Box{newBox(mgl32.Vec3{-0.5, -0.5, -0.5}, mgl32.Vec3{0.5, 0, 0.5})},
+		collides:     true,
+		lightOpacity: 1,
+	}
+
+	// bottom half with a step on the half the block faces
+	stairsShape = &BlockShape{
+		boxes: []Box{
+			newBox(mgl32.Vec3{-0.5, -0.5, -0.5}, mgl32.Vec3{0.5, 0, 0.5}),
+			newBox(mgl32.Vec3{-0.5, 0, -0.5}, mgl32.Vec3{0.5, 0.5, 0}),
+		},
+		collides:     true,
+		lightOpacity: 1,
+	}
+
+	crossShape = &BlockShape{
+		boxes: []Box{newBox(mgl32.Vec3{-0.3, -0.5, -0.3}, mgl32.Vec3{0.3, 0.3, 0.3})},
+		cross: true,
+	}
+)
+
+// Shapes by block type, blocks not listed are cubes.
+var blockShapes = map[string]*BlockShape{
+	"stone-slab":          slabShape,
+	"cobblestone-slab":    slabShape,
+	"planks-slab":         slabShape,
+	"stone-bricks-slab":   slabShape,
+	"cobblestone-stairs":  stairsShape,
+	"planks-stairs":       stairsShape,
+	"stone-bricks-stairs": stairsShape,
+	"dandelion":           crossShape,
+	"cornflower":          crossShape,
+	"white-flower":        crossShape,
+	"mushroom":            crossShape,
+}
+
+// Returns the shape of the block.
+func (b *Block) Shape() *BlockShape {
+	return blockShape(b.blockType)
+}
+
+// Returns the shape of the block type.
+func blockShape(blockType string) *BlockShape {
+	if s, ok := blockShapes[blockType]; ok {
+		return s
+	}
+	return cubeShape
+}
+
+// Returns the boxes of the block shape in the world, rotated to the block orientation.
+// Bodies collide with them if the shape collides and rays select the block through them.
+func (b *Block) Boxes() []Box {
+	center := b.WorldPos()
+	boxes := b.Shape().orientedBoxes(b.Facing(), b.Top())
+	for i, box := range boxes {
+		boxes[i] = newBox(center.Add(box.min), center.Add(box.max))
+	}
+	return boxes
+}
+
+// Returns the boxes of the shape rotated to face the direction and flipped upside down if on top.
+func (s *BlockShape) orientedBoxes(facing Direction, top bool) []Box {
+	boxes := make([]Box, len(s.boxes))
+	for i, box := range s.boxes {
+		a, b := orient(box.min, facing, top), orient(box.max, facing, top)
+		boxes[i] = newBox(
+			mgl32.Vec3{min(a.X(), b.X()), min(a.Y(), b.Y()), min(a.Z(), b.Z())},
+			mgl32.Vec3{max(a.X(), b.X()), max(a.Y(), b.Y()), max(a.Z(), b.Z())},
+		)
+	}
+	return boxes
+}
+
+// Rotates a point of a block modeled facing north around the vertical axis so it faces the direction,
+// then flips it upside down if on top.
+func orient(p mgl32.Vec3, facing Direction, top bool) mgl32.Vec3 {
+	switch facing {
+	case south:
+		p = mgl32.Vec3{-p.X(), p.Y(), -p.Z()}
+	case east:
+		p = mgl32.Vec3{-p.Z(), p.Y(), p.X()}
+	case west:
+		p = mgl32.Vec3{p.Z(), p.Y(), -p.X()}
+	}
+	if top {
+		p[1] = -p[1]
+	}
+	return p
+}
+
+// Returns the vertices of a block type centered at the origin in the state.
+// Faces on the border of the block are left out when excluded, inner faces are always kept.
+func blockVertices(atlas *TextureAtlas, blockType string, state BlockState, excludeFaces [6]bool) []BlockVertex {
+	shape := blockShape(blockType)
+	switch {
+	case shape == cubeShape:
+		return cubeVertices(atlas, blockType, Axis(state.Get(axisProperty)), excludeFaces)
+	case shape.cross:
+		return crossVertices(atlas, blockType)
+	}
+
+	texs := blocks[blockType]
+	boxes := shape.orientedBoxes(Direction(state.Get(facingProperty)), state.Get(topProperty) == 1)
+	out := make([]BlockVertex, 0)
+	for b, box := range boxes {
+		for i := range directions {
+			dir := Direction(i)
+			border := faceOffset(box, dir) == 0.5
+			if border && excludeFaces[i] || faceCovered(boxes, b, dir) {
+				continue
+			}
+
+			tex := texs[dir]
+			umin, umax, vmin, vmax := atlas.Coords(tex[0], tex[1])
+			quad := newQuad(umin, umax, vmin, vmax).TranlateDirection(dir)
+			for _, fv := range quad {
+				// map the corner of the unit cube face to the box, the texture is cut like the face
+				p := fv.pos.Mul(0.5)
+				for axis := range 3 {
+					p[axis] = lerp(p[axis]+0.5, box.min[axis], box.max[axis])
+				}
+
+				out = append(out, BlockVertex{
+					Vertex: Vertex{pos: p, tex: faceTexCoord(p, dir, umin, umax, vmin, vmax)},
+					norm:   dir.Normal(),
+					face:   dir,
+					inner:  !border,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// Returns the vertices of two quads crossing along the diagonals of the block, textured like its north face.
+// They face up so they are lit like the ground they grow on.
+func crossVertices(atlas *TextureAtlas, blockType string) []BlockVertex {
+	tex := blocks[blockType][north]
+	umin, umax, vmin, vmax := atlas.Coords(tex[0], tex[1])
+
+	diagonals := [2][2]mgl32.Vec2{
+		{{-0.5, -0.5}, {0.5, 0.5}},
+		{{-0.5, 0.5}, {0.5, -0.5}},
+	}
+	out := make([]BlockVertex, 0, len(diagonals)*len(quadIndices))
+	for _, d := range diagonals {
+		for _, fv := range newQuad(umin, umax, vmin, vmax) {
+			t := (fv.pos.X() + 1) / 2
+			fv.pos = mgl32.Vec3{lerp(t, d[0].X(), d[1].X()), fv.pos.Y() * 0.5, lerp(t, d[0].Y(), d[1].Y())}
+			out = append(out, BlockVertex{
+				Vertex: fv,
+				norm:   up.Normal(),
+				face:   up,
+				inner:  true,
+			})
+		}
+	}
+	return out
+}
+
+// Returns the offset of the box face in the direction from the center of the block, 0.5 on the border of the block.
+func faceOffset(box Box, dir Direction) float32 {
+	n := dir.Normal()
+	if n.X()+n.Y()+n.Z() > 0 {
+		return box.max.Dot(n)
+	}
+	return box.min.Dot(n)
+}
+
+// Returns true if the face of the box in the direction is covered by another box of the shape touching it.
+func faceCovered(boxes []Box, b int, dir Direction) bool {
+	box := boxes[b]
+	for o, other := range boxes {
+		if o == b || faceOffset(box, dir) != -faceOffset(other, dir.Opposite()) {
+			continue
+		}
+
+		covered := true
+		for axis := range 3 {
+			if dir.Normal()[axis] == 0 && (other.min[axis] > box.min[axis] || other.max[axis] < box.max[axis]) {
+				covered = false
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the atlas coordinates of a point on a face of a block centered at the origin.
+// Parts of faces show the matching part of the texture, the same way as the faces of a cube.
+func faceTexCoord(p mgl32.Vec3, dir Direction, umin, umax, vmin, vmax float32) mgl32.Vec2 {
+	var u, v float32
+	switch dir {
+	case north, south:
+		u, v = p.X(), p.Y()
+	case down, up:
+		u, v = p.X(), p.Z()
+	case west, east:
+		u, v = p.Z(), p.Y()
+	}
+	return mgl32.Vec2{lerp(u+0.5, umin, umax), lerp(v+0.5, vmax, vmin)}
+}
+
+// Returns the horizontal direction closest to the vector.
+func horizontalDirection(v mgl32.Vec3) Direction {
+	if abs(v.X()) > abs(v.Z()) {
+		if v.X() > 0 {
+			return east
+		}
+		return west
+	}
+	if v.Z() > 0 {
+		return south
+	}
+	return north
+}
@@ -0,0 +1,205 @@
+package game
+
+import "math"
+
+// OpenSimplex2 noise (fast variant).
+// Smoother than perlin noise with fewer directional artifacts, it hashes the lattice points
+// with the seed directly so it needs no permutation table.
+
+const (
+	simplexPrimeX         int64 = 0x5205402B9270C86F
+	simplexPrimeY         int64 = 0x598CD327003817B5
+	simplexPrimeZ         int64 = 0x5BCC226E9FA0BACB
+	simplexHashMultiplier int64 = 0x53A3F72DEEC546F5
+	simplexSeedFlip3D     int64 = -0x52D547B2E96ED629
+
+	simplexSkew2D      = 0.366025403784439
+	simplexUnskew2D    = -0.21132486540518713
+	simplexRoot3Over3  = 0.577350269189626
+	simplexRotate3D    = simplexUnskew2D
+	simplexRSquared2D  = 0.5
+	simplexRSquared3D  = 0.6
+	simplexNormalize2D = 0.01001634121365712
+	simplexNormalize3D = 0.07969837668935331
+
+	// gradient tables hold a power of two number of vectors so the hash can be masked
+	simplexGrads2DExponent = 7
+	simplexGrads3DExponent = 8
+)
+
+var (
+	simplexGradients2D = newSimplexGradients2D()
+	simplexGradients3D = newSimplexGradients3D()
+)
+
+// Returns 24 unit vectors spaced every 15 degrees, repeated to fill the table.
+func newSimplexGradients2D() []float32 {
+	out := make([]float32, 0, 2<<simplexGrads2DExponent)
+	for i := 0; len(out) < cap(out); i++ {
+		angle := (7.5 + 15*float64(i%24)) * math.Pi / 180
+		out = append(out, float32(math.Cos(angle)/simplexNormalize2D), float32(math.Sin(angle)/simplexNormalize2D))
+	}
+	return out
+}
+
+// Returns 48 vectors, four around each edge direction of a cube, repeated to fill the table.
+// Vectors are padded to 4 values so the hash can index them with a mask.
+func newSimplexGradients3D() []float32 {
+	grads := [][3]float64{}
+	for _, axes := range [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 2, 0}} {
+		a, b, c := axes[0], axes[1], axes[2]
+		for _, sa := range []float64{1, -1} {
+			for _, sb := range []float64{1, -1} {
+				for _, v := range [][3]float64{
+					{2.22474487139, 2.22474487139, -1},
+					{2.22474487139, 2.22474487139, 1},
+					{3.0862664687972017, 1.1721513422464978, 0},
+					{1.1721513422464978, 3.0862664687972017, 0},
+				} {
+					var g [3]float64
+					g[a], g[b], g[c] = sa*v[0], sb*v[1], v[2]
+					grads = append(grads, g)
+				}
+			}
+		}
+	}
+
+	out := make([]float32, 0, 4<<simplexGrads3DExponent)
+	for i := 0; len(out) < cap(out); i++ {
+		g := grads[i%len(grads)]
+		out = append(out,
+			float32(g[0]/simplexNormalize3D),
+			float32(g[1]/simplexNormalize3D),
+			float32(g[2]/simplexNormalize3D),
+			0,
+		)
+	}
+	return out
+}
+
+// Returns 2D simplex noise in [-1,1].
+func simplexNoise2D(seed int64, x, y float64) float32 {
+	// skew to the lattice of squares made of two triangles
+	s := simplexSkew2D * (x + y)
+	xs, ys := x+s, y+s
+
+	xsb, ysb := int64(math.Floor(xs)), int64(math.Floor(ys))
+	xi, yi := float32(xs-float64(xsb)), float32(ys-float64(ysb))
+	xsbp, ysbp := xsb*simplexPrimeX, ysb*simplexPrimeY
+
+	// unskew back to the distance from the first vertex
+	t := (xi + yi) * simplexUnskew2D
+	dx0, dy0 := xi+t, yi+t
+
+	var value float32
+	a0 := simplexRSquared2D - dx0*dx0 - dy0*dy0
+	if a0 > 0 {
+		value = (a0 * a0) * (a0 * a0) * simplexGrad2D(seed, xsbp, ysbp, dx0, dy0)
+	}
+
+	// opposite vertex of the square
+	a1 := float32(2*(1+2*simplexUnskew2D)*(1/simplexUnskew2D+2))*t + (float32(-2*(1+2*simplexUnskew2D)*(1+2*simplexUnskew2D)) + a0)
+	if a1 > 0 {
+		dx1 := dx0 - float32(1+2*simplexUnskew2D)
+		dy1 := dy0 - float32(1+2*simplexUnskew2D)
+		value += (a1 * a1) * (a1 * a1) * simplexGrad2D(seed, xsbp+simplexPrimeX, ysbp+simplexPrimeY, dx1, dy1)
+	}
+
+	// middle vertex of the triangle
+	if dy0 > dx0 {
+		dx2 := dx0 - float32(simplexUnskew2D)
+		dy2 := dy0 - float32(simplexUnskew2D+1)
+		a2 := simplexRSquared2D - dx2*dx2 - dy2*dy2
+		if a2 > 0 {
+			value += (a2 * a2) * (a2 * a2) * simplexGrad2D(seed, xsbp, ysbp+simplexPrimeY, dx2, dy2)
+		}
+	} else {
+		dx2 := dx0 - float32(simplexUnskew2D+1)
+		dy2 := dy0 - float32(simplexUnskew2D)
+		a2 := simplexRSquared2D - dx2*dx2 - dy2*dy2
+		if a2 > 0 {
+			value += (a2 * a2) * (a2 * a2) * simplexGrad2D(seed, xsbp+simplexPrimeX, ysbp, dx2, dy2)
+		}
+	}
+
+	return value
+}
+
+func simplexGrad2D(seed, xsvp, ysvp int64, dx, dy float32) float32 {
+	hash := seed ^ xsvp ^ ysvp
+	hash *= simplexHashMultiplier
+	hash ^= hash >> (64 - simplexGrads2DExponent + 1)
+	gi := int(hash) & ((1<<simplexGrads2DExponent - 1) << 1)
+	return simplexGradients2D[gi]*dx + simplexGradients2D[gi|1]*dy
+}
+
+// Returns 3D simplex noise in [-1,1].
+// The lattice is rotated so the xz planes look good, which suits terrain where y is up.
+func simplexNoise3D(seed int64, x, y, z float64) float32 {
+	xz := x + z
+	s2 := xz * simplexRotate3D
+	yy := y * simplexRoot3Over3
+	xr := x + s2 + yy
+	zr := z + s2 + yy
+	yr := xz*-simplexRoot3Over3 + yy
+
+	// two offset cubic lattices, each contributes its closest vertex and the next one
+	xrb, yrb, zrb := int64(math.Round(xr)), int64(math.Round(yr)), int64(math.Round(zr))
+	xri, yri, zri := float32(xr-float64(xrb)), float32(yr-float64(yrb)), float32(zr-float64(zrb))
+
+	xNSign, yNSign, zNSign := int64(-1-xri)|1, int64(-1-yri)|1, int64(-1-zri)|1
+	ax0, ay0, az0 := float32(xNSign)*-xri, float32(yNSign)*-yri, float32(zNSign)*-zri
+	xrbp, yrbp, zrbp := xrb*simplexPrimeX, yrb*simplexPrimeY, zrb*simplexPrimeZ
+
+	var value float32
+	a := (simplexRSquared3D - xri*xri) - (yri*yri + zri*zri)
+	for l := 0; ; l++ {
+		if a > 0 {
+			value += (a * a) * (a * a) * simplexGrad3D(seed, xrbp, yrbp, zrbp, xri, yri, zri)
+		}
+
+		switch {
+		case ax0 >= ay0 && ax0 >= az0:
+			if b := a + ax0 + ax0; b > 1 {
+				b -= 1
+				value += (b * b) * (b * b) * simplexGrad3D(seed, xrbp-xNSign*simplexPrimeX, yrbp, zrbp, xri+float32(xNSign), yri, zri)
+			}
+		case ay0 > ax0 && ay0 >= az0:
+			if b := a + ay0 + ay0; b > 1 {
+				b -= 1
+				value += (b * b) * (b * b) * simplexGrad3D(seed, xrbp, yrbp-yNSign*simplexPrimeY, zrbp, xri, yri+float32(yNSign), zri)
+			}
+		default:
+			if b := a + az0 + az0; b > 1 {
+				b -= 1
+				value += (b * b) * (b * b) * simplexGrad3D(seed, xrbp, yrbp, zrbp-zNSign*simplexPrimeZ, xri, yri, zri+float32(zNSign))
+			}
+		}
+
+		if l == 1 {
+			break
+		}
+
+		// move to the second lattice
+		ax0, ay0, az0 = 0.5-ax0, 0.5-ay0, 0.5-az0
+		xri, yri, zri = float32(xNSign)*ax0, float32(yNSign)*ay0, float32(zNSign)*az0
+		a += (0.75 - ax0) - (ay0 + az0)
+
+		xrbp += (xNSign >> 1) & simplexPrimeX
+		yrbp += (yNSign >> 1) & simplexPrimeY
+		zrbp += (zNSign >> 1) & simplexPrimeZ
+		xNSign, yNSign, zNSign = -xNSign, -yNSign, -zNSign
+
+		seed ^= simplexSeedFlip3D
+	}
+
+	return value
+}
+
+func simplexGrad3D(seed, xrvp, yrvp, zrvp int64, dx, dy, dz float32) float32 {
+	hash := (seed ^ xrvp) ^ (yrvp ^ zrvp)
+	hash *= simplexHashMultiplier
+	hash ^= hash >> (64 - simplexGrads3DExponent + 2)
+	gi := int(hash) & ((1<<simplexGrads3DExponent - 1) << 2)
+	return simplexGradients3D[gi]*dx + simplexGradients3D[gi|1]*dy + simplexGradients3D[gi|2]*dz
+}
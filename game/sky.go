@@ -0,0 +1,89 @@
+package game
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Sky draws the sky behind the world as a dome colored from the horizon up, with the sun and the moon.
+// The dome is a quad covering the screen where each pixel is colored by the direction it looks at,
+// so it is infinitely far and follows the camera.
+type Sky struct {
+	// shader program
+	shader *Shader
+
+	// gpu info
+	vao       uint32
+	vbo       uint32
+	vertCount int
+}
+
+// Distance fog, terrain fades into the horizon color before the edge of the loaded chunks.
+const (
+	fogEnd   = visibleRadius - chunkWidth
+	fogStart = fogEnd * 0.6
+)
+
+func newSky(shader *Shader) *Sky {
+	return &Sky{shader: shader}
+}
+
+// Initialize the sky quad on the GPU.
+func (s *Sky) Init() {
+	gl.UseProgram(s.shader.handle)
+
+	gl.GenVertexArrays(1, &s.vao)
+	gl.BindVertexArray(s.vao)
+	gl.GenBuffers(1, &s.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+
+	vertAttrib := uint32(gl.GetAttribLocation(s.shader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 2, gl.FLOAT, false, 2*4, 0)
+
+	s.Buffer()
+}
+
+// Sends the quad covering the screen to the GPU.
+func (s *Sky) Buffer() {
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+
+	buffer := []float32{}
+	for _, v := range newQuad(0, 1, 0, 1) {
+		buffer = append(buffer, v.pos.X(), v.pos.Y())
+	}
+	s.vertCount = len(buffer) / 2
+
+	gl.BufferData(gl.ARRAY_BUFFER, len(buffer)*4, gl.Ptr(buffer), gl.STATIC_DRAW)
+}
+
+// Draws the sky seen by the camera lit by the light.
+// Should be drawn first, it doesn't write depth so the world is drawn over it.
+func (s *Sky) Draw(camera *Camera, light *Light) {
+	gl.UseProgram(s.shader.handle)
+	gl.BindVertexArray(s.vao)
+
+	// only the rotation of the camera matters for the direction of the pixels
+	rotation := mgl32.LookAtV(mgl32.Vec3{}, camera.view, camera.up)
+	invView := camera.projection.Mul4(rotation).Inv()
+	invViewUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("invView\x00"))
+	gl.UniformMatrix4fv(invViewUniform, 1, false, &invView[0])
+
+	skyUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("skyColor\x00"))
+	gl.Uniform3fv(skyUniform, 1, &light.sky[0])
+
+	horizonUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("horizonColor\x00"))
+	gl.Uniform3fv(horizonUniform, 1, &light.horizon[0])
+
+	sunUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("sunDir\x00"))
+	gl.Uniform3fv(sunUniform, 1, &light.sun[0])
+
+	moonUniform := gl.GetUniformLocation(s.shader.handle, gl.Str("moonDir\x00"))
+	gl.Uniform3fv(moonUniform, 1, &light.moon[0])
+
+	gl.DepthMask(false)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(s.vertCount))
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthMask(true)
+}
@@ -0,0 +1,252 @@
+package game
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// GeneratorStage is one step of the world generation pipeline.
+// Stages read and modify the chunk buffer left by the previous stages,
+// they should only depend on the buffer and their noise so they can be reordered or run alone.
+type GeneratorStage interface {
+	// name of the stage in generatorStages
+	Name() string
+
+	// fills or modifies the blocks of the chunk
+	Generate(buf *ChunkBuffer)
+}
+
+// ChunkBuffer holds the blocks of a chunk while it goes through the generator stages.
+type ChunkBuffer struct {
+	// origin of the chunk in the world
+	pos mgl32.Vec3
+
+	// biome of each column
+	biomes [chunkWidth][chunkWidth]BiomeID
+
+	// block types, empty for air
+	blocks BlockTypes
+}
+
+// Constructors of the generator stages by name.
+// Worlds store the names of their stages so the registered names should never change.
+var generatorStages = map[string]func(g *WorldGenerator) GeneratorStage{
+	"heightmap":  func(g *WorldGenerator) GeneratorStage { return &HeightmapStage{g} },
+	"surface":    func(g *WorldGenerator) GeneratorStage { return &SurfaceStage{g} },
+	"caves":      func(g *WorldGenerator) GeneratorStage { return &CaveStage{g} },
+	"deposits":   func(g *WorldGenerator) GeneratorStage { return &DepositStage{g} },
+	"ores":       func(g *WorldGenerator) GeneratorStage { return &FeatureStage{g, &OreFeature{g, oreVeins}} },
+	"sea":        func(g *WorldGenerator) GeneratorStage { return &SeaStage{g} },
+	"trees":      func(g *WorldGenerator) GeneratorStage { return &FeatureStage{g, newTreeFeature(g)} },
+	"structures": func(g *WorldGenerator) GeneratorStage { return &FeatureStage{g, newStructureFeature(g)} },
+}
+
+// Stages of new worlds.
+var defaultGeneratorStages = []string{"heightmap", "surface", "caves", "deposits", "ores", "sea", "trees", "structures"}
+
+// Stages of the worlds created before the stages were stored, which predate ores, sea and structures.
+var legacyGeneratorStages = []string{"heightmap", "surface", "caves", "deposits", "trees"}
+
+const (
+	// heightmap
+	bedrockHeight = 5
+
+	// surface
+	surfaceDepth = 4
+	snowHeight   = 150
+
+	// caves
+	caveThreshold = 0.725
+
+	// deposits
+	depositThreshold     = 0.77
+	gravel2Threshold     = 0.775
+	cobblestoneThreshold = 0.25
+
+	// sea
+	seaLevel = 97
+
+	// trees
+	treeAttempts     = 8
+	treeTrunkHeight  = 7
+	treeLeavesWidth  = 6
+	treeLeavesHeight = 5
+	treeFallout      = 0.05
+)
+
+func newChunkBuffer(pos mgl32.Vec3, height int, biomes [chunkWidth][chunkWidth]BiomeID) *ChunkBuffer {
+	return &ChunkBuffer{
+		pos:    pos,
+		biomes: biomes,
+		blocks: newBlockTypes(height),
+	}
+}
+
+// Returns the number of blocks of the chunk along y.
+func (b *ChunkBuffer) Height() int {
+	return len(b.blocks[0])
+}
+
+// Returns true if the position is inside the chunk.
+func (b *ChunkBuffer) InBounds(x, y, z int) bool {
+	return x >= 0 && x < chunkWidth && y >= 0 && y < b.Height() && z >= 0 && z < chunkWidth
+}
+
+// Returns the height of the highest block of a column or -1 if it is empty.
+func (b *ChunkBuffer) Ground(x, z int) int {
+	for y := b.Height() - 1; y >= 0; y-- {
+		if b.blocks[x][y][z] != "" {
+			return y
+		}
+	}
+	return -1
+}
+
+// Fills the terrain with stone up to the height map and bedrock at the bottom.
+type HeightmapStage struct {
+	gen *WorldGenerator
+}
+
+func (s *HeightmapStage) Name() string {
+	return "heightmap"
+}
+
+func (s *HeightmapStage) Generate(buf *ChunkBuffer) {
+	heights := s.gen.Heights(mgl32.Vec2{buf.pos.X(), buf.pos.Z()})
+	for x := range chunkWidth {
+		for z := range chunkWidth {
+			for y := range buf.Height() {
+				curHeight := buf.pos.Y() + float32(y)
+				if curHeight > heights[x][z] {
+					break
+				}
+
+				if curHeight <= bedrockHeight {
+					buf.blocks[x][y][z] = "bedrock"
+				} else {
+					buf.blocks[x][y][z] = "stone"
+				}
+			}
+		}
+	}
+}
+
+// Covers the top stone layers of each column with the blocks of its biome.
+// Terrain above snowHeight is covered in snow in every biome.
+type SurfaceStage struct {
+	gen *WorldGenerator
+}
+
+func (s *SurfaceStage) Name() string {
+	return "surface"
+}
+
+func (s *SurfaceStage) Generate(buf *ChunkBuffer) {
+	for x := range chunkWidth {
+		for z := range chunkWidth {
+			biome := buf.biomes[x][z].Biome()
+			ground := buf.Ground(x, z)
+			for y := max(ground-surfaceDepth, 0); y <= ground; y++ {
+				if buf.blocks[x][y][z] != "stone" {
+					continue
+				}
+
+				switch {
+				case y < ground:
+					buf.blocks[x][y][z] = biome.filler
+				case buf.pos.Y()+float32(y) > snowHeight:
+					buf.blocks[x][y][z] = "dirt-snow"
+				default:
+					buf.blocks[x][y][z] = biome.surface
+				}
+			}
+		}
+	}
+}
+
+// Carves caves out of the terrain, bedrock is never carved.
+type CaveStage struct {
+	gen *WorldGenerator
+}
+
+func (s *CaveStage) Name() string {
+	return "caves"
+}
+
+func (s *CaveStage) Generate(buf *ChunkBuffer) {
+	caves := s.gen.Caves(buf.pos)
+	for x := range chunkWidth {
+		for y := range buf.Height() {
+			for z := range chunkWidth {
+				if buf.blocks[x][y][z] != "bedrock" && caves.At(x, y, z) > caveThreshold {
+					buf.blocks[x][y][z] = ""
+				}
+			}
+		}
+	}
+}
+
+// Places patches of gravel (sandstone in deserts) in the terrain and cobblestone in the stone.
+type DepositStage struct {
+	gen *WorldGenerator
+}
+
+func (s *DepositStage) Name() string {
+	return "deposits"
+}
+
+func (s *DepositStage) Generate(buf *ChunkBuffer) {
+	gravel := s.gen.Gravel(buf.pos)
+	for x := range chunkWidth {
+		for y := range buf.Height() {
+			for z := range chunkWidth {
+				t := buf.blocks[x][y][z]
+				if t == "" || t == "bedrock" {
+					continue
+				}
+
+				g := gravel.At(x, y, z)
+				switch {
+				case g > depositThreshold && buf.biomes[x][z] == biomeDesert:
+					buf.blocks[x][y][z] = "sandstone"
+				case g > gravel2Threshold:
+					buf.blocks[x][y][z] = "gravel2"
+				case g > depositThreshold:
+					buf.blocks[x][y][z] = "gravel"
+				case g < cobblestoneThreshold && t == "stone":
+					buf.blocks[x][y][z] = "cobblestone"
+				}
+			}
+		}
+	}
+}
+
+// Fills the air of each column with water from seaLevel down to the ground.
+// The biome blocks at the bottom of the water are replaced by sand.
+type SeaStage struct {
+	gen *WorldGenerator
+}
+
+func (s *SeaStage) Name() string {
+	return "sea"
+}
+
+func (s *SeaStage) Generate(buf *ChunkBuffer) {
+	top := seaLevel - int(buf.pos.Y())
+	for x := range chunkWidth {
+		for z := range chunkWidth {
+			biome := buf.biomes[x][z].Biome()
+			for y := min(top, buf.Height()-1); y >= 0; y-- {
+				t := buf.blocks[x][y][z]
+				if t == "" {
+					buf.blocks[x][y][z] = "water"
+					continue
+				}
+
+				if y < top && (t == biome.surface || t == biome.filler) {
+					buf.blocks[x][y][z] = "sand"
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const stageTestHeight = 256
+
+// Returns a buffer of the chunk at pos with every column in the biome.
+func biomeBuffer(pos mgl32.Vec3, biome BiomeID) *ChunkBuffer {
+	var biomes [chunkWidth][chunkWidth]BiomeID
+	for x := range chunkWidth {
+		for z := range chunkWidth {
+			biomes[x][z] = biome
+		}
+	}
+	return newChunkBuffer(pos, stageTestHeight, biomes)
+}
+
+// Fills every column of the buffer with bedrock and stone up to the ground.
+func fillColumns(buf *ChunkBuffer, ground int) {
+	for x := range chunkWidth {
+		for y := 0; y <= ground; y++ {
+			for z := range chunkWidth {
+				if y <= bedrockHeight {
+					buf.blocks[x][y][z] = "bedrock"
+				} else {
+					buf.blocks[x][y][z] = "stone"
+				}
+			}
+		}
+	}
+}
+
+// Returns the number of blocks of each type in the buffer.
+func countBlocks(buf *ChunkBuffer) map[string]int {
+	counts := map[string]int{}
+	for x := range chunkWidth {
+		for y := range buf.Height() {
+			for z := range chunkWidth {
+				counts[buf.blocks[x][y][z]]++
+			}
+		}
+	}
+	return counts
+}
+
+func TestHeightmapStage(t *testing.T) {
+	gen := newWorldGenerator(1, 0, stageTestHeight, nil, nil)
+	stage := &HeightmapStage{gen}
+
+	for _, pos := range chunkSquare(2) {
+		buf := newChunkBuffer(pos, stageTestHeight, gen.Biomes(mgl32.Vec2{pos.X(), pos.Z()}))
+		stage.Generate(buf)
+
+		heights := gen.Heights(mgl32.Vec2{pos.X(), pos.Z()})
+		for x := range chunkWidth {
+			for z := range chunkWidth {
+				for y := range buf.Height() {
+					var want string
+					switch {
+					case float32(y) > heights[x][z]:
+						want = ""
+					case y <= bedrockHeight:
+						want = "bedrock"
+					default:
+						want = "stone"
+					}
+
+					if got := buf.blocks[x][y][z]; got != want {
+						t.Fatalf("chunk %v column %d,%d of height %v has %q at %d, want %q", pos, x, z, heights[x][z], got, y, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSurfaceStage(t *testing.T) {
+	gen := newWorldGenerator(1, 0, stageTestHeight, nil, nil)
+	stage := &SurfaceStage{gen}
+
+	tests := []struct {
+		name    string
+		biome   BiomeID
+		ground  int
+		surface string
+		filler  string
+	}{
+		{"plains", biomePlains, 80, "dirt-grass", "dirt"},
+		{"desert", biomeDesert, 80, "sand", "sand"},
+		{"plains above the snow", biomePlains, snowHeight + 10, "dirt-snow", "dirt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := biomeBuffer(mgl32.Vec3{}, tt.biome)
+			fillColumns(buf, tt.ground)
+			stage.Generate(buf)
+
+			for x := range chunkWidth {
+				for z := range chunkWidth {
+					for y := range buf.Height() {
+						var want string
+						switch {
+						case y > tt.ground:
+							want = ""
+						case y == tt.ground:
+							want = tt.surface
+						case y >= tt.ground-surfaceDepth:
+							want = tt.filler
+						case y <= bedrockHeight:
+							want = "bedrock"
+						default:
+							want = "stone"
+						}
+
+						if got := buf.blocks[x][y][z]; got != want {
+							t.Fatalf("column %d,%d has %q at %d, want %q", x, z, got, y, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSurfaceStageKeepsBedrock(t *testing.T) {
+	gen := newWorldGenerator(1, 0, stageTestHeight, nil, nil)
+	buf := biomeBuffer(mgl32.Vec3{}, biomePlains)
+	fillColumns(buf, bedrockHeight)
+	(&SurfaceStage{gen}).Generate(buf)
+
+	if n := countBlocks(buf)["bedrock"]; n != chunkWidth*chunkWidth*(bedrockHeight+1) {
+		t.Fatalf("%d bedrock blocks left, want %d", n, chunkWidth*chunkWidth*(bedrockHeight+1))
+	}
+}
+
+func TestCaveStageKeepsBedrock(t *testing.T) {
+	gen := newWorldGenerator(1, 0, stageTestHeight, nil, nil)
+	stage := &CaveStage{gen}
+
+	carved := 0
+	for _, pos := range chunkSquare(1) {
+		buf := biomeBuffer(pos, biomePlains)
+		fillColumns(buf, stageTestHeight-1)
+		stage.Generate(buf)
+
+		counts := countBlocks(buf)
+		if want := chunkWidth * chunkWidth * (bedrockHeight + 1); counts["bedrock"] != want {
+			t.Fatalf("chunk %v has %d bedrock blocks, want %d", pos, counts["bedrock"], want)
+		}
+		if len(counts) > 3 {
+			t.Fatalf("chunk %v has blocks other than air, stone and bedrock: %v", pos, counts)
+		}
+		carved += counts[""]
+	}
+
+	if carved == 0 {
+		t.Fatal("no cave carved")
+	}
+}
+
+func TestDepositStage(t *testing.T) {
+	gen := newWorldGenerator(1, 0, stageTestHeight, nil, nil)
+	stage := &DepositStage{gen}
+
+	tests := []struct {
+		name    string
+		biome   BiomeID
+		allowed []string
+	}{
+		{"plains", biomePlains, []string{"gravel", "gravel2", "cobblestone"}},
+		{"desert", biomeDesert, []string{"sandstone", "cobblestone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const ground = 120
+			found := map[string]int{}
+			for _, pos := range chunkSquare(1) {
+				buf := biomeBuffer(pos, tt.biome)
+				fillColumns(buf, ground)
+				stage.Generate(buf)
+
+				for x := range chunkWidth {
+					for y := range buf.Height() {
+						for z := range chunkWidth {
+							got := buf.blocks[x][y][z]
+							switch {
+							case y <= bedrockHeight:
+								if got != "bedrock" {
+									t.Fatalf("bedrock replaced by %q at %d", got, y)
+								}
+							case y > ground:
+								if got != "" {
+									t.Fatalf("air replaced by %q at %d", got, y)
+								}
+							case got != "stone":
+								found[got]++
+							}
+						}
+					}
+				}
+			}
+
+			for _, blockType := range tt.allowed {
+				if found[blockType] == 0 {
+					t.Errorf("no %s placed", blockType)
+				}
+				delete(found, blockType)
+			}
+			if len(found) > 0 {
+				t.Fatalf("unexpected deposits %v", found)
+			}
+		})
+	}
+}
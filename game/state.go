@@ -0,0 +1,196 @@
+package game
+
+import (
+	"log"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// BlockState packs the values of the state properties of a block (e.g. the level of a fluid) in bits.
+// States are persisted as is in the blocks table, so the bits of the existing properties must never move.
+type BlockState uint16
+
+// BlockProperty is a value stored in the bits of the block state.
+type BlockProperty struct {
+	// name of the property, used in logs
+	name string
+
+	// offset and number of bits of the property in the state
+	shift, bits uint
+}
+
+// Axis is the axis along which a block like a log is placed.
+type Axis uint
+
+const (
+	yAxis Axis = iota
+	xAxis
+	zAxis
+)
+
+var (
+	// level of a fluid, 0 for a source and up to fluidMaxLevel as it flows away
+	levelProperty = &BlockProperty{name: "level", shift: 0, bits: 3}
+
+	// horizontal direction the block faces
+	facingProperty = &BlockProperty{name: "facing", shift: 3, bits: 3}
+
+	// if the block is placed upside down
+	topProperty = &BlockProperty{name: "top", shift: 6, bits: 1}
+
+	// axis of the block, the faces along it show the ends of the block
+	axisProperty = &BlockProperty{name: "axis", shift: 7, bits: 2}
+)
+
+// State properties by block type, blocks not listed have none and always have the zero state.
+var blockProperties = map[string][]*BlockProperty{
+	"water":               {levelProperty},
+	"lava":                {levelProperty},
+	"stone-slab":          {topProperty},
+	"cobblestone-slab":    {topProperty},
+	"planks-slab":         {topProperty},
+	"stone-bricks-slab":   {topProperty},
+	"cobblestone-stairs":  {facingProperty, topProperty},
+	"planks-stairs":       {facingProperty, topProperty},
+	"stone-bricks-stairs": {facingProperty, topProperty},
+	"wood":                {axisProperty},
+	"white-wood":          {axisProperty},
+	"dark-wood":           {axisProperty},
+}
+
+// Returns the value of the property in the state.
+func (s BlockState) Get(p *BlockProperty) int {
+	return int(s>>p.shift) & (1<<p.bits - 1)
+}
+
+// Returns the state with the property set to the value.
+// Panics if the value doesn't fit in the bits of the property.
+func (s BlockState) With(p *BlockProperty, v int) BlockState {
+	mask := BlockState(1<<p.bits-1) << p.shift
+	if v < 0 || v >= 1<<p.bits {
+		log.Panicf("value %d out of range for block property %s", v, p.name)
+	}
+	return s&^mask | BlockState(v)<<p.shift
+}
+
+// Returns true if blocks of the type have the property.
+func hasProperty(blockType string, p *BlockProperty) bool {
+	for _, bp := range blockProperties[blockType] {
+		if bp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the value of the property of the block, 0 if its type doesn't have it.
+func (b *Block) Property(p *BlockProperty) int {
+	return b.state.Get(p)
+}
+
+// Sets the property of the block.
+// Panics if the block type doesn't have the property so states never hold values their type ignores.
+func (b *Block) SetProperty(p *BlockProperty, v int) {
+	if !hasProperty(b.blockType, p) {
+		log.Panicf("block type %s has no property %s", b.blockType, p.name)
+	}
+	b.state = b.state.With(p, v)
+}
+
+// Changes the type of the block and resets its state.
+func (b *Block) SetType(blockType string) {
+	b.blockType = blockType
+	b.state = 0
+}
+
+// Returns the fluid level of the block.
+func (b *Block) Level() int {
+	return b.Property(levelProperty)
+}
+
+// Sets the fluid level of the block.
+func (b *Block) SetLevel(level int) {
+	b.SetProperty(levelProperty, level)
+}
+
+// Returns the horizontal direction the block faces, north if its type can't be turned.
+func (b *Block) Facing() Direction {
+	return Direction(b.Property(facingProperty))
+}
+
+// Returns true if the block is placed upside down.
+func (b *Block) Top() bool {
+	return b.Property(topProperty) == 1
+}
+
+// Returns the axis of the block, vertical if its type can't be rotated.
+func (b *Block) Axis() Axis {
+	return Axis(b.Property(axisProperty))
+}
+
+// Returns the state of a block of the type placed against the target by a player looking in the view direction.
+// Blocks face the way the player looks, so stairs rise away from the player, and are on top
+// when placed under a block or against the upper half of its side.
+// Logs are placed along the axis of the face they are placed against.
+func placementState(blockType string, target *TargetBlock, view mgl32.Vec3) BlockState {
+	var state BlockState
+	if hasProperty(blockType, facingProperty) {
+		state = state.With(facingProperty, int(horizontalDirection(view)))
+	}
+
+	if hasProperty(blockType, topProperty) {
+		top := false
+		switch target.face {
+		case down:
+			top = true
+		case up:
+			top = false
+		default:
+			top = target.hitPos.Y()-floor(target.hitPos.Y()) > 0.5
+		}
+		if top {
+			state = state.With(topProperty, 1)
+		}
+	}
+
+	if hasProperty(blockType, axisProperty) {
+		axis := yAxis
+		switch target.face {
+		case west, east:
+			axis = xAxis
+		case north, south:
+			axis = zAxis
+		}
+		state = state.With(axisProperty, int(axis))
+	}
+	return state
+}
+
+// Returns the direction whose texture is shown on the face of a block with the axis
+// and if the texture is turned a quarter so its grain runs along the axis.
+// The ends of a block are the up and down textures and its sides show the north texture.
+func axisFace(axis Axis, dir Direction) (Direction, bool) {
+	switch axis {
+	case xAxis:
+		switch dir {
+		case west:
+			return down, false
+		case east:
+			return up, false
+		default:
+			return north, true
+		}
+	case zAxis:
+		switch dir {
+		case north:
+			return down, false
+		case south:
+			return up, false
+		case west, east:
+			return north, true
+		default:
+			return north, false
+		}
+	}
+	return dir, false
+}
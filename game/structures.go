@@ -0,0 +1,339 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// StructureLibrary holds the structure templates loaded from a directory of json files.
+// Each file holds one template named after the file, following the format:
+//
+//	{
+//	  "placement": "surface", "biomes": ["plains"], "spacing": 6, "chance": 0.5, "maxSlope": 1,
+//	  "key": {"C": "cobblestone", "P": "planks", ".": ""},
+//	  "layers": [["CCC", "CCC"], ["P.P", "PPP"]]
+//	}
+//
+// Layers go from the bottom up, their rows along z and the characters of a row along x.
+// Spaces keep the terrain and keys mapped to "" carve air.
+type StructureLibrary struct {
+	templates []*StructureTemplate
+	rootPath  string
+}
+
+// Where a template is placed in the terrain.
+type StructurePlacement int
+
+const (
+	// bottom layer replaces the ground, on flat land above the sea
+	placeSurface StructurePlacement = iota
+
+	// buried between a min and max height
+	placeUnderground
+)
+
+// StructureTemplate is a pre-authored group of blocks placed by the generator.
+type StructureTemplate struct {
+	// name of the file, also salts the placement of the template
+	name string
+
+	placement StructurePlacement
+
+	// biomes the template can be placed in, any if empty
+	biomes []BiomeID
+
+	// range of world heights of the bottom layer of underground templates
+	minHeight, maxHeight int
+
+	// at most one template in each square region of spacing chunks, placed with the chance
+	spacing int
+	chance  float32
+
+	// max difference of terrain height under surface templates
+	maxSlope float32
+
+	// dimensions along x, y and z
+	size [3]int
+
+	// blocks set by the template, "" for carved air
+	blocks []FeatureBlock
+}
+
+// Format of a template in the json files.
+type structureDefinition struct {
+	Placement string            `json:"placement"`
+	Biomes    []string          `json:"biomes"`
+	MinHeight int               `json:"minHeight"`
+	MaxHeight int               `json:"maxHeight"`
+	Spacing   int               `json:"spacing"`
+	Chance    float32           `json:"chance"`
+	MaxSlope  float32           `json:"maxSlope"`
+	Key       map[string]string `json:"key"`
+	Layers    [][]string        `json:"layers"`
+}
+
+// underground templates stay at least this many blocks under the terrain
+const structureCover = 4
+
+func newStructureLibrary(root string) *StructureLibrary {
+	s := &StructureLibrary{}
+	s.rootPath = root
+	s.init()
+	return s
+}
+
+// Loads and validates the templates found in the rootPath.
+func (s *StructureLibrary) init() {
+	files, err := filepath.Glob(filepath.Join(s.rootPath, "*.json"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		var def structureDefinition
+		if err := json.Unmarshal(b, &def); err != nil {
+			log.Panicf("invalid structure file %s: %v", file, err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		template, err := parseStructure(name, def)
+		if err != nil {
+			log.Panicf("invalid structure %s: %v", file, err)
+		}
+		s.templates = append(s.templates, template)
+	}
+
+	log.Printf("Loaded %d structures", len(s.templates))
+}
+
+// Converts a template definition to a template, validating block types and biomes.
+func parseStructure(name string, def structureDefinition) (*StructureTemplate, error) {
+	t := &StructureTemplate{
+		name:      name,
+		minHeight: def.MinHeight,
+		maxHeight: def.MaxHeight,
+		spacing:   def.Spacing,
+		chance:    def.Chance,
+		maxSlope:  def.MaxSlope,
+	}
+
+	switch def.Placement {
+	case "surface":
+		t.placement = placeSurface
+	case "underground":
+		t.placement = placeUnderground
+		if def.MinHeight <= bedrockHeight || def.MaxHeight < def.MinHeight {
+			return nil, fmt.Errorf("invalid height range %d-%d", def.MinHeight, def.MaxHeight)
+		}
+	default:
+		return nil, fmt.Errorf("unknown placement %q", def.Placement)
+	}
+
+	if def.Spacing <= 0 || def.Chance <= 0 || def.Chance > 1 {
+		return nil, fmt.Errorf("invalid spacing %d or chance %v", def.Spacing, def.Chance)
+	}
+
+	for _, biome := range def.Biomes {
+		id, ok := biomeByName(biome)
+		if !ok {
+			return nil, fmt.Errorf("unknown biome %q", biome)
+		}
+		t.biomes = append(t.biomes, id)
+	}
+
+	key := map[rune]string{}
+	for k, blockType := range def.Key {
+		if len([]rune(k)) != 1 || k == " " {
+			return nil, fmt.Errorf("invalid key %q", k)
+		}
+		if _, ok := blocks[blockType]; blockType != "" && !ok {
+			return nil, fmt.Errorf("unknown block type %q", blockType)
+		}
+		key[[]rune(k)[0]] = blockType
+	}
+
+	if len(def.Layers) == 0 || len(def.Layers[0]) == 0 {
+		return nil, fmt.Errorf("no layers")
+	}
+	t.size = [3]int{len([]rune(def.Layers[0][0])), len(def.Layers), len(def.Layers[0])}
+	if max(t.size[0], t.size[2]) > chunkWidth {
+		return nil, fmt.Errorf("footprint larger than a chunk")
+	}
+
+	for y, layer := range def.Layers {
+		if len(layer) != t.size[2] {
+			return nil, fmt.Errorf("layer %d has %d rows, expected %d", y, len(layer), t.size[2])
+		}
+
+		for z, row := range layer {
+			if len([]rune(row)) != t.size[0] {
+				return nil, fmt.Errorf("row %d of layer %d has %d cells, expected %d", z, y, len([]rune(row)), t.size[0])
+			}
+
+			for x, c := range []rune(row) {
+				if c == ' ' {
+					continue
+				}
+
+				blockType, ok := key[c]
+				if !ok {
+					return nil, fmt.Errorf("missing key %q", c)
+				}
+				t.blocks = append(t.blocks, FeatureBlock{[3]int{x, y, z}, blockType, true, nil})
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// Returns the id of the biome with the name.
+func biomeByName(name string) (BiomeID, bool) {
+	for id, b := range biomes {
+		if b.name == name {
+			return BiomeID(id), true
+		}
+	}
+	return 0, false
+}
+
+// Places the structure templates of the generator.
+// Each template picks a random chunk, position and rotation in every region of its spacing,
+// then is only placed if the terrain there fits it.
+type StructureFeature struct {
+	gen *WorldGenerator
+
+	templates []*StructureTemplate
+}
+
+func newStructureFeature(gen *WorldGenerator) *StructureFeature {
+	f := &StructureFeature{gen: gen}
+	if gen.structures != nil {
+		f.templates = gen.structures.templates
+	}
+	return f
+}
+
+func (f *StructureFeature) Name() string {
+	return "structures"
+}
+
+func (f *StructureFeature) Reach() int {
+	// templates are at most a chunk wide so they only reach into the next chunks
+	if len(f.templates) == 0 {
+		return 0
+	}
+	return 1
+}
+
+func (f *StructureFeature) Place(pos mgl32.Vec3, rng *rand.Rand, place func(FeatureBlock)) {
+	for _, t := range f.templates {
+		origin, rotation, ok := f.origin(t, pos)
+		if !ok {
+			continue
+		}
+
+		for _, b := range t.blocks {
+			x, z := rotate(b.pos[0], b.pos[2], t.size[0], t.size[2], rotation)
+			place(FeatureBlock{[3]int{origin[0] + x, origin[1] + b.pos[1], origin[2] + z}, b.blockType, true, nil})
+		}
+	}
+}
+
+// Returns the world position of the corner of the template and its rotation
+// if the template is placed in the chunk at pos.
+func (f *StructureFeature) origin(t *StructureTemplate, pos mgl32.Vec3) (origin [3]int, rotation int, ok bool) {
+	cx, cz := floorDiv(int(pos.X()), chunkWidth), floorDiv(int(pos.Z()), chunkWidth)
+	rx, rz := floorDiv(cx, t.spacing), floorDiv(cz, t.spacing)
+
+	// the region decides the position so there is at most one template in it
+	rng := f.gen.ChunkRand(mgl32.Vec3{float32(rx), 0, float32(rz)}, featureSalt(t.name))
+	chance := rng.Float32()
+	px, pz := rng.Intn(t.spacing), rng.Intn(t.spacing)
+	x, z := rng.Intn(chunkWidth), rng.Intn(chunkWidth)
+	rotation = rng.Intn(4)
+	y := 0
+	if t.placement == placeUnderground {
+		y = t.minHeight + rng.Intn(t.maxHeight-t.minHeight+1)
+	}
+
+	if chance >= t.chance || rx*t.spacing+px != cx || rz*t.spacing+pz != cz {
+		return origin, rotation, false
+	}
+
+	// footprint of the rotated template
+	sx, sz := t.size[0], t.size[2]
+	if rotation%2 == 1 {
+		sx, sz = sz, sx
+	}
+	wx, wz := cx*chunkWidth+x, cz*chunkWidth+z
+	center := [2]float32{float32(wx + sx/2), float32(wz + sz/2)}
+
+	if len(t.biomes) > 0 && !slices.Contains(t.biomes, f.gen.Biome(center[0], center[1])) {
+		return origin, rotation, false
+	}
+
+	switch t.placement {
+	case placeSurface:
+		// flat ground, checked at the corners and center of the footprint
+		lo, hi := float32(math.MaxFloat32), float32(-math.MaxFloat32)
+		for _, p := range [][2]float32{
+			center,
+			{float32(wx), float32(wz)},
+			{float32(wx + sx - 1), float32(wz)},
+			{float32(wx), float32(wz + sz - 1)},
+			{float32(wx + sx - 1), float32(wz + sz - 1)},
+		} {
+			h := floor(f.gen.Height(p[0], p[1]))
+			lo, hi = min(lo, h), max(hi, h)
+		}
+		if hi-lo > t.maxSlope {
+			return origin, rotation, false
+		}
+
+		y = int(lo)
+		if f.gen.HasStage("sea") && y < seaLevel {
+			return origin, rotation, false
+		}
+		if f.gen.HasStage("caves") && f.gen.Cave(int(center[0]), y-1, int(center[1])) {
+			return origin, rotation, false
+		}
+		if y+t.size[1] >= f.gen.maxHeight {
+			return origin, rotation, false
+		}
+	case placeUnderground:
+		if y+t.size[1] > int(f.gen.Height(center[0], center[1]))-structureCover {
+			return origin, rotation, false
+		}
+	}
+
+	return [3]int{wx, y, wz}, rotation, true
+}
+
+// Rotates a position in a footprint of width by depth by quarter turns around the y axis.
+func rotate(x, z, width, depth, quarters int) (int, int) {
+	switch quarters % 4 {
+	case 1:
+		return depth - 1 - z, x
+	case 2:
+		return width - 1 - x, depth - 1 - z
+	case 3:
+		return z, width - 1 - x
+	default:
+		return x, z
+	}
+}
@@ -0,0 +1,164 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// TextRenderer draws strings on the screen with the bitmap font of the texture atlas.
+// Strings added during a frame are batched into one buffer of glyph quads and drawn with a single call.
+type TextRenderer struct {
+	shader *Shader
+	atlas  *TextureAtlas
+
+	// vertices of the strings added since the last draw
+	buffer []float32
+
+	// gpu info
+	vertCount int
+	vao, vbo  uint32
+}
+
+// Horizontal alignment of text relative to its position.
+type TextAlign uint
+
+const (
+	alignLeft TextAlign = iota
+	alignCenter
+	alignRight
+)
+
+const (
+	// distance between the top of two lines relative to the text size
+	textLineSpacing = 1.25
+
+	// offset of the shadow relative to the text size (one font pixel) and its brightness
+	textShadowOffset     = 1.0 / 8
+	textShadowBrightness = 0.25
+
+	// text draws over everything else on the screen
+	textDepth       = -0.9
+	textShadowDepth = -0.89
+)
+
+// Default colors of text.
+var (
+	textWhite  = mgl32.Vec3{1, 1, 1}
+	textYellow = mgl32.Vec3{1, 1, 0.3}
+)
+
+func newTextRenderer(shader *Shader, atlas *TextureAtlas) *TextRenderer {
+	t := &TextRenderer{
+		shader: shader,
+		atlas:  atlas,
+	}
+	return t
+}
+
+// Initialize the text renderer metadata on the GPU.
+func (t *TextRenderer) Init() {
+	gl.UseProgram(t.shader.handle)
+
+	gl.GenVertexArrays(1, &t.vao)
+	gl.BindVertexArray(t.vao)
+	gl.GenBuffers(1, &t.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
+
+	// configure the attributes
+	vertAttrib := uint32(gl.GetAttribLocation(t.shader.handle, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointerWithOffset(vertAttrib, 3, gl.FLOAT, false, 8*4, 0)
+
+	texCoordAtrrib := uint32(gl.GetAttribLocation(t.shader.handle, gl.Str("texCoord\x00")))
+	gl.EnableVertexAttribArray(texCoordAtrrib)
+	gl.VertexAttribPointerWithOffset(texCoordAtrrib, 2, gl.FLOAT, false, 8*4, 3*4)
+
+	colorAtrrib := uint32(gl.GetAttribLocation(t.shader.handle, gl.Str("color\x00")))
+	gl.EnableVertexAttribArray(colorAtrrib)
+	gl.VertexAttribPointerWithOffset(colorAtrrib, 3, gl.FLOAT, false, 8*4, 5*4)
+}
+
+// Returns the width in pixels of a line of text.
+func textWidth(line string, size float32) float32 {
+	n := len([]rune(line))
+	if n == 0 {
+		return 0
+	}
+	return float32(n-1)*size*fontAdvance + size*fontGlyphWidth
+}
+
+// Adds text to be drawn on the next frame with a drop shadow.
+// The position is in screen pixels from the top left corner of the window and the size is the height of a glyph in pixels.
+// Each line is aligned on pos, the text starts at pos for alignLeft, is centered on it for alignCenter and ends at it for alignRight.
+func (t *TextRenderer) Add(text string, pos mgl32.Vec2, size float32, align TextAlign, color mgl32.Vec3) {
+	shadow := color.Mul(textShadowBrightness)
+	offset := size * textShadowOffset
+
+	for i, line := range strings.Split(text, "\n") {
+		x := pos.X()
+		switch align {
+		case alignCenter:
+			x -= textWidth(line, size) / 2
+		case alignRight:
+			x -= textWidth(line, size)
+		}
+		y := pos.Y() + float32(i)*size*textLineSpacing
+
+		for j, c := range []rune(line) {
+			if c == ' ' {
+				continue
+			}
+
+			glyph := t.atlas.Glyph(c)
+			gx := x + float32(j)*size*fontAdvance
+			t.appendGlyph(glyph, gx+offset, y+offset, size, textShadowDepth, shadow)
+			t.appendGlyph(glyph, gx, y, size, textDepth, color)
+		}
+	}
+}
+
+// Appends the quad of a glyph with its top left corner at x,y (in pixels).
+func (t *TextRenderer) appendGlyph(glyph [2]int, x, y, size, depth float32, color mgl32.Vec3) {
+	umin, umax, vmin, vmax := t.atlas.Coords(glyph[0], glyph[1])
+	quad := newQuad(umin, umax, vmin, vmax)
+
+	// quads span -1 to 1, map them to the glyph rectangle in normalized device coordinates
+	half := size / 2
+	cx := 2*(x+half)/windowWidth - 1
+	cy := 1 - 2*(y+half)/windowHeight
+	sx := 2 * half / windowWidth
+	sy := 2 * half / windowHeight
+
+	for _, v := range quad {
+		t.buffer = append(t.buffer,
+			cx+v.pos.X()*sx, cy+v.pos.Y()*sy, depth,
+			v.tex.X(), v.tex.Y(),
+			color.X(), color.Y(), color.Z(),
+		)
+	}
+}
+
+// Draws the text added since the last draw and clears it.
+// Does not apply view or model transformations because it is not world positioned.
+func (t *TextRenderer) Draw() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	gl.UseProgram(t.shader.handle)
+	gl.BindVertexArray(t.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(t.buffer)*4, gl.Ptr(t.buffer), gl.STREAM_DRAW)
+	t.vertCount = len(t.buffer) / 8
+	t.buffer = t.buffer[:0]
+
+	texUniform := gl.GetUniformLocation(t.shader.handle, gl.Str("tex\x00"))
+	gl.Uniform1i(texUniform, 0)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, t.atlas.texture.handle)
+
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(t.vertCount))
+}
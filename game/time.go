@@ -0,0 +1,183 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// WorldTime is the time of day of a world.
+// It advances one tick per simulation step from sunrise at 0 and can be frozen.
+// The sun and moon go around the world once a day, lighting it and coloring the sky.
+type WorldTime struct {
+	// ticks since the world was created
+	ticks int64
+
+	// stops the time of day from advancing
+	frozen bool
+}
+
+const (
+	// ticks in a day
+	dayLength = 24000
+
+	// time new worlds start at
+	worldStartTime = 1000
+
+	// the sun path is tilted to the south so it is never straight above
+	sunTilt = 0.35
+
+	// light level under the moon and under the sun
+	nightLightLevel = 0.25
+	dayLightLevel   = 1.0
+)
+
+// Named times of day, in ticks after sunrise.
+var timesOfDay = map[string]int64{
+	"sunrise":  0,
+	"day":      1000,
+	"noon":     6000,
+	"sunset":   12000,
+	"night":    13000,
+	"midnight": 18000,
+}
+
+// Colors of the sky, overhead and at the horizon where it meets the fog.
+var (
+	daySkyColor       = mgl32.Vec3{0.53, 0.75, 1.0}
+	nightSkyColor     = mgl32.Vec3{0.02, 0.03, 0.08}
+	sunsetSkyColor    = mgl32.Vec3{0.95, 0.55, 0.3}
+	dayHorizonColor   = mgl32.Vec3{0.75, 0.85, 0.97}
+	nightHorizonColor = mgl32.Vec3{0.05, 0.06, 0.12}
+)
+
+func newWorldTime(ticks int64, frozen bool) *WorldTime {
+	return &WorldTime{ticks: ticks, frozen: frozen}
+}
+
+// Advances the time by one tick unless it is frozen.
+func (t *WorldTime) Advance() {
+	if !t.frozen {
+		t.ticks++
+	}
+}
+
+// Sets the time of day in ticks after sunrise, keeping the current day.
+func (t *WorldTime) Set(timeOfDay int64) {
+	t.ticks = t.ticks - t.ticks%dayLength + (timeOfDay%dayLength+dayLength)%dayLength
+}
+
+// Moves the time forward by ticks.
+func (t *WorldTime) Add(ticks int64) {
+	t.ticks = max(t.ticks+ticks, 0)
+}
+
+// Returns the ticks since sunrise of the current day.
+func (t *WorldTime) Ticks() int64 {
+	return t.ticks % dayLength
+}
+
+// Returns the time of day in [0,1), 0 at sunrise and 0.5 at sunset.
+func (t *WorldTime) TimeOfDay() float32 {
+	return float32(t.Ticks()) / dayLength
+}
+
+// Returns the direction pointing at the sun.
+// The sun rises in the east, is highest at noon and sets in the west.
+func (t *WorldTime) SunDirection() mgl32.Vec3 {
+	angle := float64(t.TimeOfDay()) * 2 * math.Pi
+	return mgl32.Vec3{float32(math.Cos(angle)), float32(math.Sin(angle)), sunTilt}.Normalize()
+}
+
+// Returns the direction pointing at the moon, opposite to the sun.
+func (t *WorldTime) MoonDirection() mgl32.Vec3 {
+	return t.SunDirection().Mul(-1)
+}
+
+// Returns the direction pointing at the light casting shadows, the sun during the day and the moon at night.
+func (t *WorldTime) LightDirection() mgl32.Vec3 {
+	sun := t.SunDirection()
+	if sun.Y() >= 0 {
+		return sun
+	}
+	return t.MoonDirection()
+}
+
+// Returns the level of the light, fading between night and day as the sun crosses the horizon.
+func (t *WorldTime) LightLevel() float32 {
+	return lerp(t.daylight(), nightLightLevel, dayLightLevel)
+}
+
+// Returns the color of the sky, blue during the day, dark at night and orange around sunrise and sunset.
+func (t *WorldTime) SkyColor() mgl32.Vec3 {
+	daylight := t.daylight()
+	sky := nightSkyColor.Mul(1 - daylight).Add(daySkyColor.Mul(daylight))
+
+	glow := 0.6 * t.sunsetGlow()
+	return sky.Mul(1 - glow).Add(sunsetSkyColor.Mul(glow))
+}
+
+// Returns the color of the sky at the horizon, paler than overhead and glowing more around sunrise and sunset.
+// Distant terrain fades into it.
+func (t *WorldTime) HorizonColor() mgl32.Vec3 {
+	daylight := t.daylight()
+	horizon := nightHorizonColor.Mul(1 - daylight).Add(dayHorizonColor.Mul(daylight))
+
+	glow := 0.8 * t.sunsetGlow()
+	return horizon.Mul(1 - glow).Add(sunsetSkyColor.Mul(glow))
+}
+
+// Returns how much the sky glows as the sun crosses the horizon, from 0 to 1 when the sun is on it.
+func (t *WorldTime) sunsetGlow() float32 {
+	return 1 - smoothstep(0, 0.3, abs(t.SunDirection().Y()))
+}
+
+// Returns how much the sun lights the world, from 0 at night to 1 during the day.
+func (t *WorldTime) daylight() float32 {
+	return smoothstep(-0.1, 0.15, t.SunDirection().Y())
+}
+
+// Returns the console command reading and changing the time.
+func timeCommand(t *WorldTime) *Command {
+	return &Command{
+		usage: "time set|add <ticks|sunrise|day|noon|sunset|night|midnight>, time freeze|unfreeze|query",
+		run: func(args []string) (string, error) {
+			if len(args) == 0 {
+				return "", fmt.Errorf("missing action")
+			}
+
+			switch args[0] {
+			case "set", "add":
+				if len(args) != 2 {
+					return "", fmt.Errorf("missing ticks")
+				}
+
+				ticks, ok := timesOfDay[args[1]]
+				if !ok {
+					var err error
+					if ticks, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+						return "", fmt.Errorf("invalid ticks %q", args[1])
+					}
+				}
+
+				if args[0] == "set" {
+					t.Set(ticks)
+				} else {
+					t.Add(ticks)
+				}
+				return fmt.Sprintf("time is %d", t.Ticks()), nil
+			case "freeze":
+				t.frozen = true
+				return fmt.Sprintf("time frozen at %d", t.Ticks()), nil
+			case "unfreeze":
+				t.frozen = false
+				return "time unfrozen", nil
+			case "query":
+				return fmt.Sprintf("time is %d, day %d", t.Ticks(), t.ticks/dayLength), nil
+			}
+			return "", fmt.Errorf("unknown action %q", args[0])
+		},
+	}
+}
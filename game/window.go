@@ -19,7 +19,8 @@ const (
 	windowHeight = 1000
 )
 
-func newWindow() *Window {
+// Creates the window, hidden windows are used to render offscreen.
+func newWindow(visible bool) *Window {
 	runtime.LockOSThread()
 	if err := glfw.Init(); err != nil {
 		log.Fatalln("failed to initialize glfw:", err)
@@ -30,6 +31,9 @@ func newWindow() *Window {
 	glfw.WindowHint(glfw.ContextVersionMinor, 1)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	if !visible {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
 
 	window, err := glfw.CreateWindow(windowWidth, windowHeight, "minecraft", nil, nil)
 	if err != nil {
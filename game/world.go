@@ -27,7 +27,24 @@ type World struct {
 	// db instance
 	db *Database
 
-	spawnQueue *Queue[mgl32.Vec3]
+	// orders the spawns of the chunks around the player
+	loader *ChunkLoader
+
+	// simulation ticks since the world was loaded
+	tick int
+
+	// block updates waiting for their tick, and the tick each position is scheduled for
+	scheduled map[int][]mgl32.Vec3
+	pending   map[mgl32.Vec3]int
+
+	// chunks changed by the updates of the current tick
+	changedChunks map[*Chunk]bool
+
+	// range of world heights holding blocks, multiples of chunkSectionSize
+	minHeight, maxHeight int
+
+	// time of day, advancing with the ticks
+	time *WorldTime
 }
 
 const (
@@ -36,6 +53,10 @@ const (
 	worldBedrock   = 0.0
 	worldMaxHeight = 200.0
 
+	// range of heights of new worlds
+	defaultMinHeight = 0
+	defaultMaxHeight = 256
+
 	// rendering
 	visibleRadius     = 120.0  // blocks
 	spawnRadius       = 5      // chunks
@@ -47,15 +68,25 @@ const (
 	seed                        = 10
 )
 
-func newWorld(chunkShader, chunkShadowMapShader *Shader, atlas *TextureAtlas, worldId int, db *Database) *World {
+func newWorld(chunkShader, chunkShadowMapShader *Shader, atlas *TextureAtlas, worldId int, minHeight, maxHeight int, worldTime *WorldTime, generatorStages []string, structures *StructureLibrary, db *Database) *World {
+	if minHeight%chunkSectionSize != 0 || maxHeight%chunkSectionSize != 0 || minHeight >= maxHeight {
+		log.Panicf("invalid world height range %d-%d", minHeight, maxHeight)
+	}
+
 	w := &World{}
 	w.id = worldId
+	w.minHeight = minHeight
+	w.maxHeight = maxHeight
+	w.time = worldTime
 	w.chunkShader = chunkShader
 	w.chunkShadowMapShader = chunkShadowMapShader
 	w.chunks = newVecMap[Chunk]()
 	w.atlas = atlas
-	w.generator = newWorldGenerator(seed)
-	w.spawnQueue = newQueue[mgl32.Vec3]()
+	w.generator = newWorldGenerator(seed, minHeight, maxHeight, generatorStages, structures)
+	w.loader = newChunkLoader()
+	w.scheduled = make(map[int][]mgl32.Vec3)
+	w.pending = make(map[mgl32.Vec3]int)
+	w.changedChunks = make(map[*Chunk]bool)
 	w.db = db
 	return w
 }
@@ -64,7 +95,7 @@ func (w *World) Init() {
 	s := playerSpawnRadius
 	for i := range s {
 		for j := range s {
-			p := mgl32.Vec3{float32(chunkWidth * i), 0, float32(chunkWidth * j)}
+			p := mgl32.Vec3{float32(chunkWidth * i), float32(w.minHeight), float32(chunkWidth * j)}
 			w.SpawnChunk(p)
 		}
 	}
@@ -84,17 +115,18 @@ func (w *World) SaveBlock(b *Block) {
 	if blockEntity != nil {
 		blockEntity.blockType = b.blockType
 		blockEntity.active = b.active
+		blockEntity.state = b.state
 		w.db.UpdateBlock(blockEntity)
 	} else {
-		w.db.CreateBlock(b.chunk.id, b.i, b.j, b.k, b.blockType, b.active)
+		w.db.CreateBlock(b.chunk.id, b.i, b.j, b.k, b.blockType, b.active, b.state)
 	}
 }
 
 // Spawns a new chunk at the given position.
-// The param should a be a "valid" chunk position.
+// The param should a be a "valid" chunk position, at the bottom of the world.
 func (w *World) SpawnChunk(pos mgl32.Vec3) *Chunk {
 	if int(pos.X())%chunkWidth != 0 ||
-		int(pos.Y())%chunkHeight != 0 ||
+		int(pos.Y()) != w.minHeight ||
 		int(pos.Z())%chunkWidth != 0 {
 		log.Panicf("invalid chunk pos %v", pos)
 	}
@@ -105,7 +137,9 @@ func (w *World) SpawnChunk(pos mgl32.Vec3) *Chunk {
 	}
 
 	// init default chunk, attribs, pointers and save
-	chunk := newChunk(w.chunkShader, w.chunkShadowMapShader, w.atlas, pos)
+	chunk := newChunk(w.chunkShader, w.chunkShadowMapShader, w.atlas, pos, w.maxHeight-w.minHeight)
+	chunk.discoverBlock = w.ExistingBlock
+	chunk.discoverLight = w.Light
 	w.chunks.Set(pos, chunk)
 	s := w.generator.Terrain(chunk.pos)
 	chunk.Init(s)
@@ -116,16 +150,17 @@ func (w *World) SpawnChunk(pos mgl32.Vec3) *Chunk {
 	if chunkEntity != nil {
 		persistedBlocks := w.db.Blocks(chunkEntity.id)
 		for _, be := range persistedBlocks {
-			block := chunk.blocks[be.i][be.j][be.k]
+			block := chunk.Block(be.i, be.j, be.k)
 			block.active = be.active
 			block.blockType = be.blockType
+			block.state = be.state
 		}
 
 		// importantly set the chunk ID
 		chunk.id = chunkEntity.id
 	}
 
-	w.SpawnTrees(chunk)
+	w.LightChunk(chunk)
 	chunk.Buffer()
 	return chunk
 }
@@ -139,8 +174,14 @@ func (w *World) DespawnChunk(c *Chunk) {
 // Returns the ground block from the provided coordinate.
 // i.e. the y for a given x,z.
 func (w *World) Ground(x, z float32) *Block {
-	for y := chunkHeight - 1; y >= 0; y-- {
-		b := w.Block(mgl32.Vec3{x, float32(y), z})
+	chunkPos, i, _, k := w.Position(mgl32.Vec3{x, float32(w.minHeight), z})
+	chunk := w.chunks.Get(chunkPos)
+	if chunk == nil {
+		chunk = w.SpawnChunk(chunkPos)
+	}
+
+	for j := chunk.Height() - 1; j >= 0; j-- {
+		b := chunk.existingBlock(i, j, k)
 		if b != nil && b.active {
 			return b
 		}
@@ -149,12 +190,14 @@ func (w *World) Ground(x, z float32) *Block {
 }
 
 // Returns the nearby blocks from a postion (i.e the walls, floor and cieling).
+// Positions outside the world height are surrounded by air.
 func (w *World) SurroundingBoxes(p ...mgl32.Vec3) []Box {
 	// blocks occupied byt he body
 	bodyBlocks := map[mgl32.Vec3]*Block{}
 	for _, v := range p {
-		b := w.Block(v)
-		bodyBlocks[b.WorldPos()] = b
+		if b := w.Block(v); b != nil {
+			bodyBlocks[b.WorldPos()] = b
+		}
 	}
 
 	// relative surrounding vectors
@@ -176,8 +219,8 @@ func (w *World) SurroundingBoxes(p ...mgl32.Vec3) []Box {
 
 			// check if block is active and not part of the occupying block
 			existingBody := bodyBlocks[surPos]
-			if existingBody == nil && sur.active {
-				surroundings = append(surroundings, sur.Box())
+			if existingBody == nil && sur != nil && sur.Solid() {
+				surroundings = append(surroundings, sur.Boxes()...)
 			}
 
 		}
@@ -186,19 +229,23 @@ func (w *World) SurroundingBoxes(p ...mgl32.Vec3) []Box {
 	return surroundings
 }
 
-// Places the chunks surrounding the position in a spawn queue.
-// Spawns a square around postion.
-func (w *World) SpawnSurroundings(p mgl32.Vec3) {
+// Requests the spawn of the missing chunks surrounding the position from the loader.
+// Spawns a square around postion, nearest chunks and chunks in the view frustrum first.
+// Every chunk is taken to be in view if the frustrum is nil.
+func (w *World) SpawnSurroundings(p mgl32.Vec3, view *Frustrum) {
+	w.loader.BeginPass()
 	startChunk, _, _, _ := w.Position(p.Sub(mgl32.Vec3{spawnRadius * chunkWidth, 0, spawnRadius * chunkWidth}))
 	for x := range spawnRadius * 2 {
 		for z := range spawnRadius * 2 {
 			pos := startChunk.Add(mgl32.Vec3{float32(x * chunkWidth), 0, float32(z * chunkWidth)})
-			centerPos := pos.Add(mgl32.Vec3{chunkWidth / 2, chunkHeight / 2, chunkWidth / 2})
+			centerPos := pos.Add(mgl32.Vec3{chunkWidth / 2, float32(w.maxHeight-w.minHeight) / 2, chunkWidth / 2})
 			if centerPos.Sub(p).Len() <= visibleRadius && w.chunks.Get(pos) == nil {
-				w.spawnQueue.Push(&pos)
+				box := newBox(pos, pos.Add(mgl32.Vec3{chunkWidth, float32(w.maxHeight - w.minHeight), chunkWidth}))
+				w.loader.Request(pos, box.Distance(p), view == nil || view.Intersects(box))
 			}
 		}
 	}
+	w.loader.EndPass()
 }
 
 // Spawns a circle around passed postion.
@@ -212,9 +259,12 @@ func (w *World) SpawnRadius(center mgl32.Vec3) {
 
 	v := mgl32.Vec2{r, 0}
 	for range iterations {
-		// simply call block to trigger a spawn if chunk doesnt exist
+		// spawn the chunk if it doesnt exist
 		p := center.Add(mgl32.Vec3{v.X(), 0, v.Y()})
-		w.Block(p)
+		p[1] = float32(w.minHeight)
+		if chunkPos, _, _, _ := w.Position(p); w.chunks.Get(chunkPos) == nil {
+			w.SpawnChunk(chunkPos)
+		}
 
 		// rotate vector
 		m := mgl32.Rotate2D(theta)
@@ -228,7 +278,7 @@ func (w *World) SpawnRadius(center mgl32.Vec3) {
 func (w *World) CollectChunks(p mgl32.Vec3, cull func(c *Chunk) bool) []*Chunk {
 	o := make([]*Chunk, 0)
 	for _, c := range w.chunks.All() {
-		chunkCenter := c.pos.Add(mgl32.Vec3{chunkWidth / 2, chunkHeight / 2, chunkWidth / 2})
+		chunkCenter := c.pos.Add(mgl32.Vec3{chunkWidth / 2, float32(c.Height()) / 2, chunkWidth / 2})
 		diff := p.Sub(chunkCenter)
 		diffl := diff.Len()
 		if diffl <= visibleRadius {
@@ -246,19 +296,119 @@ func (w *World) CollectChunks(p mgl32.Vec3, cull func(c *Chunk) bool) []*Chunk {
 // Returns the block at the given positions.
 // This takes any position in the world, including non-round postions.
 // Will spawn chunk if it doesnt exist yet.
+// Returns nil outside the world height and in sections without blocks, where there is only air.
 func (w *World) Block(pos mgl32.Vec3) *Block {
+	if !w.InHeight(pos) {
+		return nil
+	}
+
 	chunkPos, i, j, k := w.Position(pos)
 	chunk := w.chunks.Get(chunkPos)
 	if chunk == nil {
 		chunk = w.SpawnChunk(chunkPos)
 	}
 
-	block := chunk.blocks[i][j][k]
-	return block
+	return chunk.existingBlock(i, j, k)
+}
+
+// Returns the block at the given position to be changed, creating its section if it has no blocks yet.
+// Returns nil outside the world height or if its chunk is not spawned.
+func (w *World) EditBlock(pos mgl32.Vec3) *Block {
+	if !w.InHeight(pos) {
+		return nil
+	}
+
+	chunkPos, i, j, k := w.Position(pos)
+	chunk := w.chunks.Get(chunkPos)
+	if chunk == nil {
+		return nil
+	}
+	return chunk.Block(i, j, k)
+}
+
+// Returns true if the position is between the min and max height of the world.
+func (w *World) InHeight(pos mgl32.Vec3) bool {
+	return pos.Y() >= float32(w.minHeight) && pos.Y() < float32(w.maxHeight)
+}
+
+// Returns the block at the given position if its chunk is spawned and its section has blocks, nil otherwise.
+// Unlike Block it never spawns chunks, so updates spreading through the world stop at the loaded area.
+func (w *World) LoadedBlock(pos mgl32.Vec3) *Block {
+	if !w.InHeight(pos) {
+		return nil
+	}
+
+	chunkPos, i, j, k := w.Position(pos)
+	chunk := w.chunks.Get(chunkPos)
+	if chunk == nil {
+		return nil
+	}
+	return chunk.existingBlock(i, j, k)
+}
+
+// Returns the block at the world coordinates if its chunk is spawned and its section has blocks, nil otherwise.
+// Same as LoadedBlock with integer coordinates.
+func (w *World) ExistingBlock(x, y, z int) *Block {
+	if y < w.minHeight || y >= w.maxHeight {
+		return nil
+	}
+
+	chunkPos, i, j, k := w.Position(mgl32.Vec3{float32(x), float32(y), float32(z)})
+	chunk := w.chunks.Get(chunkPos)
+	if chunk == nil {
+		return nil
+	}
+	return chunk.existingBlock(i, j, k)
+}
+
+// Schedules an update of the block at the position in delay ticks.
+// A position is only scheduled once, at its earliest tick.
+func (w *World) ScheduleTick(pos mgl32.Vec3, delay int) {
+	t := w.tick + delay
+	if scheduled, ok := w.pending[pos]; ok && scheduled <= t {
+		return
+	}
+
+	w.pending[pos] = t
+	w.scheduled[t] = append(w.scheduled[t], pos)
+}
+
+// Advances the world by one simulation tick.
+// Runs the block updates scheduled for the tick, rebuffers the chunks they changed and advances the time of day.
+func (w *World) Tick() {
+	due := w.scheduled[w.tick]
+	delete(w.scheduled, w.tick)
+
+	updates := 0
+	for _, pos := range due {
+		// skip positions rescheduled to an earlier tick
+		if w.pending[pos] != w.tick {
+			continue
+		}
+		delete(w.pending, pos)
+
+		// spread big changes over several ticks
+		if updates == fluidUpdatesPerTick {
+			w.ScheduleTick(pos, 1)
+			continue
+		}
+
+		w.updateFluid(pos)
+		updates++
+	}
+
+	for c := range w.changedChunks {
+		c.Buffer()
+	}
+	clear(w.changedChunks)
+	w.tick++
+	w.time.Advance()
 }
 
 // This takes any position in the world, including non-round postions
 // and returns the containing chunk and block positions.
+// Chunks span the whole world height so j is the height above the bottom of the world,
+// out of the chunk outside the world height.
 func (w *World) Position(pos mgl32.Vec3) (chunk mgl32.Vec3, i int, j int, k int) {
 	floor := func(v float32) int {
 		return int(math.Floor(float64(v)))
@@ -267,7 +417,7 @@ func (w *World) Position(pos mgl32.Vec3) (chunk mgl32.Vec3, i int, j int, k int)
 
 	// remainder will be the offset inside chunk
 	xoffset := x % chunkWidth
-	yoffset := y % chunkHeight
+	yoffset := y - w.minHeight
 	zoffset := z % chunkWidth
 
 	// if the offsets are negative we flip
@@ -276,101 +426,38 @@ func (w *World) Position(pos mgl32.Vec3) (chunk mgl32.Vec3, i int, j int, k int)
 		// offset = chunkSize - (-offset)
 		xoffset = chunkWidth + xoffset
 	}
-	if yoffset < 0 {
-		yoffset = chunkHeight + yoffset
-	}
 	if zoffset < 0 {
 		zoffset = chunkWidth + zoffset
 	}
 
 	// get the chunk origin position
 	startX := x - xoffset
-	startY := y - yoffset
+	startY := w.minHeight
 	startZ := z - zoffset
 
 	chunkPos := mgl32.Vec3{float32(startX), float32(startY), float32(startZ)}
 	return chunkPos, xoffset, yoffset, zoffset
 }
 
-// Spawns one frame worth of chunks from the spawn queue.
+// Spawns one frame worth of chunks from the loader.
+// Chunks spawned since they were requested don't count.
 func (w *World) ProcessSpawnQueue() {
-	for range deferredChunkSpawnsPerFrame {
-		pos := w.spawnQueue.Pop()
-		if pos != nil {
-			w.SpawnChunk(*pos)
+	for spawned := 0; spawned < deferredChunkSpawnsPerFrame; {
+		pos, ok := w.loader.Pop()
+		if !ok {
+			return
 		}
-	}
-}
 
-func (w *World) DrainSpawnQueue() {
-	last := w.spawnQueue.Pop()
-	for last != nil {
-		w.SpawnChunk(*last)
-		last = w.spawnQueue.Pop()
+		if w.chunks.Get(pos) == nil {
+			w.SpawnChunk(pos)
+			spawned++
+		}
 	}
 }
 
-func (w *World) SpawnTrees(chunk *Chunk) {
-	biome := w.generator.Biome(mgl32.Vec2{chunk.pos.X(), chunk.pos.Z()})
-	trunkHeight := float32(7.0)
-	width := float32(6.0)
-	leavesHeight := float32(5.0)
-	trees := w.generator.TreeDistribution(mgl32.Vec2{chunk.pos.X(), chunk.pos.Z()})
-	fallout := w.generator.TreeFallout(width, leavesHeight, width)
-	for x, dist := range trees {
-		for z, prob := range dist {
-			if prob <= 0.65 {
-				continue
-			}
-
-			b := w.Ground(chunk.pos.X()+float32(x), chunk.pos.Z()+float32(z))
-			if b == nil {
-				continue
-			}
-
-			base := b.WorldPos()
-
-			// trunk
-			for i := 1; i < int(trunkHeight); i++ {
-				block := w.Block(base.Add(mgl32.Vec3{0, float32(i), 0}))
-				block.active = true
-				if biome < 0.4 {
-					block.blockType = "cactus"
-				} else {
-					if int(prob*100)%2 == 0 {
-						block.blockType = "dark-wood"
-					} else if int(prob*1000)%2 == 0 {
-						block.blockType = "white-wood"
-					} else {
-						block.blockType = "wood"
-					}
-				}
-			}
-
-			// dont draw leaves
-			if biome <= 0.4 {
-				continue
-			}
-
-			// leaves
-			corner := base.Add(mgl32.Vec3{-(width + 1) / 2, trunkHeight - 2, -(width + 1) / 2})
-			for x := range int(width) {
-				for y := range int(leavesHeight) {
-					for z := range int(width) {
-						block := w.Block(corner.Add(mgl32.Vec3{float32(x), float32(y), float32(z)}))
-						fall := fallout[x][y][z]
-						if fall < 0.05 {
-							block.active = false
-							continue
-						}
-
-						if block.blockType != "wood" {
-							block.active = true
-							block.blockType = "leaves"
-						}
-					}
-				}
-			}
-		}
+// Spawns every chunk pending in the loader.
+func (w *World) DrainSpawnQueue() {
+	for pos, ok := w.loader.Pop(); ok; pos, ok = w.loader.Pop() {
+		w.SpawnChunk(pos)
 	}
 }
@@ -1,7 +1,59 @@
 package main
 
-import "minecraft/game"
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"minecraft/game"
+	"strconv"
+	"strings"
+)
 
 func main() {
-	game.Start()
+	capture := flag.String("capture", "", "render a world to this PNG file without playing it")
+	world := flag.String("world", "", "name of the world to capture")
+	pos := flag.String("pos", "100.5,125.5,100.5", "camera position of the capture as x,y,z")
+	dir := flag.String("dir", "0,0,-1", "direction the camera of the capture looks at as x,y,z")
+	ticks := flag.Int64("time", -1, "time of day of the capture in ticks after sunrise, the world time if negative")
+	flag.Parse()
+
+	if *capture == "" {
+		game.Start()
+		return
+	}
+
+	opts := game.CaptureOptions{World: *world, Time: *ticks, Output: *capture}
+	var err error
+	if opts.Pos, err = parseVec(*pos); err != nil {
+		log.Fatalf("invalid -pos: %v", err)
+	}
+	if opts.Dir, err = parseVec(*dir); err != nil {
+		log.Fatalf("invalid -dir: %v", err)
+	}
+	if opts.Dir == [3]float32{} {
+		log.Fatal("invalid -dir: the direction is zero")
+	}
+	game.Capture(opts)
+}
+
+// Parses a vector written as x,y,z.
+func parseVec(s string) ([3]float32, error) {
+	var v [3]float32
+	parts := strings.Split(s, ",")
+	if len(parts) != len(v) {
+		return v, fmt.Errorf("expected x,y,z, got %q", s)
+	}
+
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return v, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return v, fmt.Errorf("%q is not a finite number", p)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
 }
@@ -0,0 +1,26 @@
+#version 330
+
+// texture being blurred
+uniform sampler2D image;
+
+// blurs along x if true, along y otherwise
+uniform bool horizontal;
+
+in vec2 texCoord;
+
+out vec4 color;
+
+// weights of the center texel and of the texels on each side, a gaussian kernel
+const float weights[5] = float[5](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+void main() {
+    vec2 texel = 1.0 / textureSize(image, 0);
+    vec2 step = horizontal ? vec2(texel.x, 0.0) : vec2(0.0, texel.y);
+
+    vec3 result = texture(image, texCoord).rgb * weights[0];
+    for (int i = 1; i < 5; ++i) {
+        result += texture(image, texCoord + step * i).rgb * weights[i];
+        result += texture(image, texCoord - step * i).rgb * weights[i];
+    }
+    color = vec4(result, 1.0);
+}
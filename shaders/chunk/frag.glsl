@@ -12,8 +12,36 @@ uniform float lightLevel;
 // position of camera
 uniform vec3 cameraPos;
 
-// depth map
-uniform sampler2D shadowMap;
+// most cascades the depth map can have
+#define MAX_CASCADES 4
+
+// depth map, a layer per cascade
+uniform sampler2DArray shadowMap;
+
+// light matrix of each cascade
+uniform mat4 lightSpaceMatrices[MAX_CASCADES];
+
+// distance along the camera view where each cascade ends
+uniform float cascadeFar[MAX_CASCADES];
+
+// size of a texel of each cascade in the world
+uniform float cascadeTexelSize[MAX_CASCADES];
+
+// number of cascades in use
+uniform int cascadeCount;
+
+// direction the camera looks at
+uniform vec3 cameraDir;
+
+// color of the horizon distant fragments fade into
+uniform vec3 fogColor;
+
+// horizontal distances from the camera where the fog starts and where it hides everything
+uniform float fogStart;
+uniform float fogEnd;
+
+// how far the chunk faded in since it was spawned, in [0,1]
+uniform float fade;
 
 // texture coordinate
 in vec2 fragTexCoord;
@@ -27,35 +55,48 @@ in vec3 fragNorm;
 // world position
 in vec3 fragPos;
 
-// light world position
-in vec4 fragPosLight;
+// sky and block light levels in [0,1]
+in vec2 fragLight;
+
+// ambient occlusion in [0,1]
+in float fragAO;
+
+// light emitted by the block in [0,1]
+in float fragEmission;
 
 // final color
-out vec4 color;
+layout(location = 0) out vec4 color;
 
-float ShadowCalculation(vec4 fragPosLightSpace, vec3 lightDir) {
-    // perspective divide
-    vec3 projCoords = fragPosLightSpace.xyz / fragPosLightSpace.w;
+// light of the emitting blocks, blurred into the bloom
+layout(location = 1) out vec4 bright;
 
-    // transform to [0,1] range
+// Returns how much the fragment is in shadow in a cascade.
+float CascadeShadow(int cascade, vec3 norm, vec3 lightDir) {
+    // move the position along the normal by texels so faces don't shadow themselves
+    vec3 pos = fragPos + norm * cascadeTexelSize[cascade] * 1.5;
+    vec4 fragPosLightSpace = lightSpaceMatrices[cascade] * vec4(pos, 1.0);
+
+    // perspective divide and transform to [0,1] range
+    vec3 projCoords = fragPosLightSpace.xyz / fragPosLightSpace.w;
     projCoords = projCoords * 0.5 + 0.5;
 
-    // get closest depth value from light's perspective
-    float closestDepth = texture(shadowMap, projCoords.xy).r;
+    // nothing casts shadows beyond the far plane of the light
+    if (projCoords.z > 1.0) {
+        return 0.0;
+    }
 
     // get depth of current fragment from light's perspective
     float currentDepth = projCoords.z;
 
     // calc bias based on light angle
-    float bias = max(0.03 * (1.0 - dot(fragNorm, lightDir)), 0.005);
-    // float bias = 0.005;
+    float bias = max(0.002 * (1.0 - dot(norm, lightDir)), 0.0005);
 
     // check whether current frag pos is in shadow using 5x5 PCF
     float shadow = 0.0;
-    vec2 texelSize = 1.0 / textureSize(shadowMap, 0);
+    vec2 texelSize = 1.0 / textureSize(shadowMap, 0).xy;
     for(int x = -2; x <= 2; ++x) {
         for(int y = -2; y <= 2; ++y) {
-            float pcfDepth = texture(shadowMap, projCoords.xy + vec2(x, y) * texelSize).r;
+            float pcfDepth = texture(shadowMap, vec3(projCoords.xy + vec2(x, y) * texelSize, cascade)).r;
             shadow += currentDepth - bias > pcfDepth ? 1.0 : 0.0;
         }
     }
@@ -64,9 +105,62 @@ float ShadowCalculation(vec4 fragPosLightSpace, vec3 lightDir) {
     return shadow;
 }
 
+// Returns how much the fragment is in shadow, from the nearest cascade covering it.
+// The last tenth of each cascade blends into the next one so the change of resolution isn't seen.
+float ShadowCalculation(vec3 norm, vec3 lightDir) {
+    float depth = dot(fragPos - cameraPos, cameraDir);
+    for (int i = 0; i < cascadeCount; ++i) {
+        if (depth > cascadeFar[i]) {
+            continue;
+        }
+
+        float start = 0.0;
+        if (i > 0) {
+            start = cascadeFar[i - 1];
+        }
+        float blendStart = mix(start, cascadeFar[i], 0.9);
+        float shadow = CascadeShadow(i, norm, lightDir);
+        if (depth <= blendStart) {
+            return shadow;
+        }
+
+        // the last cascade fades out instead
+        float next = 0.0;
+        if (i + 1 < cascadeCount) {
+            next = CascadeShadow(i + 1, norm, lightDir);
+        }
+        return mix(shadow, next, (depth - blendStart) / (cascadeFar[i] - blendStart));
+    }
+    return 0.0;
+}
+
+// 4x4 ordered dithering thresholds
+const float bayer[16] = float[16](
+    0.0, 8.0, 2.0, 10.0,
+    12.0, 4.0, 14.0, 6.0,
+    3.0, 11.0, 1.0, 9.0,
+    15.0, 7.0, 13.0, 5.0
+);
+
+// Returns the dithering threshold of the pixel in (0,1).
+float Dither() {
+    ivec2 p = ivec2(gl_FragCoord.xy) % 4;
+    return (bayer[p.y * 4 + p.x] + 0.5) / 16.0;
+}
+
+// Returns the brightness of a voxel light level, each level below the max dims it by a fifth.
+float LightCurve(float level) {
+    return pow(0.8, (1.0 - level) * 15.0);
+}
+
 void main() {
+    // chunks fade in by drawing a growing share of their pixels
+    if (fade < Dither()) {
+        discard;
+    }
+
     vec4 c = texture(tex, fragTexCoord);
-    // make transparent
+    // holes of cutout blocks (e.g. leaves) are transparent
     if (c.a < 0.1) {
         discard;
     }
@@ -82,6 +176,7 @@ void main() {
     float shininess = 8;
     vec3 lightColor = vec3(1.0, 1.0, 1.0);
     lightColor = lightColor * lightLevel;
+    vec3 blockLightColor = vec3(1.0, 0.85, 0.6);
 
     // diffuse lighting component
     vec3 norm = normalize(fragNorm);
@@ -94,12 +189,21 @@ void main() {
     float spec = pow(max(dot(viewDir, reflectDir), 0.0), shininess);
 
     // shadow
-    float shadow = ShadowCalculation(fragPosLight, lightDir);
+    float shadow = ShadowCalculation(norm, lightDir);
 
     // combine
     vec3 diffuse = diff * lightColor;
     vec3 ambient = ambientStrength * lightColor;
     vec3 specular = specularStrength * spec * lightColor;
-    vec4 total = vec4(ambient + (1.0 - shadow) * (diffuse + specular), 1.0);
+    // the sun only reaches as far as the sky light, emitting blocks light the rest
+    vec3 sunlight = (ambient + (1.0 - shadow) * (diffuse + specular)) * LightCurve(fragLight.x);
+    vec3 blocklight = blockLightColor * LightCurve(fragLight.y);
+    float occlusion = mix(0.45, 1.0, fragAO);
+    vec4 total = vec4(max(sunlight, blocklight) * occlusion, 1.0);
     color = total * c;
+
+    // fog, by horizontal distance like the chunks are loaded
+    float fog = smoothstep(fogStart, fogEnd, length(fragPos.xz - cameraPos.xz));
+    color.rgb = mix(color.rgb, fogColor, fog);
+    bright = vec4(color.rgb * fragEmission * (1.0 - fog), color.a);
 }
@@ -12,9 +12,6 @@ uniform vec3 lookedAtBlock;
 // is looking at chunk
 uniform bool isLooking;
 
-// light matrix
-uniform mat4 lightSpaceMatrix;
-
 // position of vertex without tranform
 in vec3 vert;
 
@@ -24,12 +21,23 @@ in vec3 normal;
 // texture coordinate in the atlas
 in vec2 texCoord;
 
+// sky and block light levels in [0,1]
+in vec2 light;
+
+// ambient occlusion of the vertex in [0,1], 0 for a corner between solid blocks
+in float ao;
+
+// light emitted by the block in [0,1]
+in float emission;
+
 // outputs
 out vec2 fragTexCoord;
 out float selected;
 out vec3 fragNorm;
 out vec3 fragPos;
-out vec4 fragPosLight;
+out vec2 fragLight;
+out float fragAO;
+out float fragEmission;
 
 void main() {
     // world pos of vertex
@@ -51,7 +59,9 @@ void main() {
     fragNorm = mat3(transpose(inverse(model))) * normal;
 
     fragTexCoord = texCoord;
+    fragLight = light;
+    fragAO = ao;
+    fragEmission = emission;
     fragPos = vec3(pos);
-    fragPosLight = lightSpaceMatrix * pos;
     gl_Position = view * pos;
 }
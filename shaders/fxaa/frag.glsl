@@ -0,0 +1,57 @@
+#version 330
+
+// final colors of the frame
+uniform sampler2D image;
+
+in vec2 texCoord;
+
+out vec4 color;
+
+// limits of the search along the edges
+const float spanMax = 8.0;
+const float reduceMul = 1.0 / 8.0;
+const float reduceMin = 1.0 / 128.0;
+
+// Returns the perceived brightness of a color.
+float Luma(vec3 c) {
+    return dot(c, vec3(0.299, 0.587, 0.114));
+}
+
+// Fast approximate anti-aliasing, blurs the pixels along the edges found from the contrast of their luma.
+void main() {
+    vec2 texel = 1.0 / textureSize(image, 0);
+
+    float lumaNW = Luma(texture(image, texCoord + vec2(-1.0, -1.0) * texel).rgb);
+    float lumaNE = Luma(texture(image, texCoord + vec2(1.0, -1.0) * texel).rgb);
+    float lumaSW = Luma(texture(image, texCoord + vec2(-1.0, 1.0) * texel).rgb);
+    float lumaSE = Luma(texture(image, texCoord + vec2(1.0, 1.0) * texel).rgb);
+    vec3 center = texture(image, texCoord).rgb;
+    float lumaM = Luma(center);
+
+    float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+    float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+
+    // direction along the edge, perpendicular to the gradient
+    vec2 dir = vec2(
+        -((lumaNW + lumaNE) - (lumaSW + lumaSE)),
+        (lumaNW + lumaSW) - (lumaNE + lumaSE)
+    );
+    float dirReduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * 0.25 * reduceMul, reduceMin);
+    float rcpDirMin = 1.0 / (min(abs(dir.x), abs(dir.y)) + dirReduce);
+    dir = clamp(dir * rcpDirMin, vec2(-spanMax), vec2(spanMax)) * texel;
+
+    vec3 rgbA = 0.5 * (
+        texture(image, texCoord + dir * (1.0 / 3.0 - 0.5)).rgb +
+        texture(image, texCoord + dir * (2.0 / 3.0 - 0.5)).rgb);
+    vec3 rgbB = rgbA * 0.5 + 0.25 * (
+        texture(image, texCoord + dir * -0.5).rgb +
+        texture(image, texCoord + dir * 0.5).rgb);
+
+    // the wider sample crossed another edge, keep the narrow one
+    float lumaB = Luma(rgbB);
+    if (lumaB < lumaMin || lumaB > lumaMax) {
+        color = vec4(rgbA, 1.0);
+    } else {
+        color = vec4(rgbB, 1.0);
+    }
+}
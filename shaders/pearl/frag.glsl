@@ -4,7 +4,10 @@ uniform sampler2D tex;
 
 in vec2 TexCoords;
 
-out vec4 color;
+layout(location = 0) out vec4 color;
+
+// pearls and items don't glow with the bloom
+layout(location = 1) out vec4 bright;
 
 void main() {
     vec4 c = texture(tex, TexCoords);
@@ -13,4 +16,5 @@ void main() {
     }
 
     color = c;
+    bright = vec4(0.0);
 }
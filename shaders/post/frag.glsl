@@ -0,0 +1,64 @@
+#version 330
+
+// world rendered in high dynamic range
+uniform sampler2D scene;
+
+// blurred light of the emissive blocks
+uniform sampler2D bloom;
+
+// enabled effects
+uniform bool bloomEnabled;
+uniform bool tonemapEnabled;
+uniform bool gammaEnabled;
+
+// if the camera is under water and the underwater effect is enabled
+uniform bool underwater;
+
+// strength of the bloom added to the scene
+uniform float bloomStrength;
+
+// brightness of the scene before it is tonemapped
+uniform float exposure;
+
+// gamma of the display
+uniform float gamma;
+
+in vec2 texCoord;
+
+out vec4 color;
+
+// color the view is tinted with under water
+const vec3 waterTint = vec3(0.1, 0.35, 0.6);
+
+// Maps high dynamic range colors to [0,1] with a filmic curve (ACES fitted by Narkowicz).
+vec3 Tonemap(vec3 c) {
+    c *= exposure;
+    return clamp((c * (2.51 * c + 0.03)) / (c * (2.43 * c + 0.59) + 0.14), 0.0, 1.0);
+}
+
+void main() {
+    vec3 c = texture(scene, texCoord).rgb;
+    if (bloomEnabled) {
+        c += texture(bloom, texCoord).rgb * bloomStrength;
+    }
+
+    // water absorbs the red first, the view turns blue and darker
+    if (underwater) {
+        c = mix(c * vec3(0.5, 0.8, 1.0), waterTint, 0.35);
+    }
+
+    // the scene colors are gamma encoded like the textures, effects on light work on linear colors
+    if (gammaEnabled) {
+        c = pow(max(c, 0.0), vec3(gamma));
+    }
+
+    if (tonemapEnabled) {
+        c = Tonemap(c);
+    }
+
+    if (gammaEnabled) {
+        c = pow(c, vec3(1.0 / gamma));
+    }
+
+    color = vec4(clamp(c, 0.0, 1.0), 1.0);
+}
@@ -0,0 +1,12 @@
+#version 330
+
+// corner of the quad covering the screen
+in vec2 vert;
+
+// texture coordinate of the pixel in the source textures
+out vec2 texCoord;
+
+void main() {
+    texCoord = vert * 0.5 + 0.5;
+    gl_Position = vec4(vert, 0.0, 1.0);
+}
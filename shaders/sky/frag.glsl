@@ -0,0 +1,57 @@
+#version 330
+
+// inverse of the camera projection and rotation, without its position
+uniform mat4 invView;
+
+// color of the sky overhead
+uniform vec3 skyColor;
+
+// color of the sky at the horizon, same as the fog
+uniform vec3 horizonColor;
+
+// directions pointing at the sun and the moon
+uniform vec3 sunDir;
+uniform vec3 moonDir;
+
+// position on the screen in [-1,1]
+in vec2 screenPos;
+
+// final color
+layout(location = 0) out vec4 color;
+
+// the sky doesn't glow with the bloom
+layout(location = 1) out vec4 bright;
+
+// cosine of the angular radius of the discs
+const float sunSize = 0.9992;
+const float moonSize = 0.9995;
+
+const vec3 sunColor = vec3(1.0, 0.95, 0.8);
+const vec3 moonColor = vec3(0.85, 0.88, 0.95);
+
+// Returns how much of the disc of a body in the direction covers the view direction, blurred on its edge.
+float Disc(vec3 dir, vec3 bodyDir, float size) {
+    return smoothstep(size, size + 0.0002, dot(dir, bodyDir));
+}
+
+void main() {
+    // direction the pixel looks at
+    vec4 far = invView * vec4(screenPos, 1.0, 1.0);
+    vec3 dir = normalize(far.xyz / far.w);
+
+    // gradient from the horizon up, the dome is the horizon color below it
+    float height = max(dir.y, 0.0);
+    vec3 sky = mix(horizonColor, skyColor, smoothstep(0.0, 0.45, height));
+
+    // halo around the sun, brighter around sunrise and sunset when the sky is colored
+    float sunAngle = max(dot(dir, sunDir), 0.0);
+    sky += sunColor * pow(sunAngle, 32.0) * 0.25;
+
+    // the bodies set behind the horizon
+    float above = smoothstep(-0.02, 0.02, dir.y);
+    sky = mix(sky, sunColor, Disc(dir, sunDir, sunSize) * above);
+    sky = mix(sky, moonColor, Disc(dir, moonDir, moonSize) * above);
+
+    color = vec4(sky, 1.0);
+    bright = vec4(0.0);
+}
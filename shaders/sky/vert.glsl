@@ -0,0 +1,13 @@
+#version 330
+
+// corner of the quad covering the screen
+in vec2 vert;
+
+// position on the screen in [-1,1]
+out vec2 screenPos;
+
+void main() {
+    screenPos = vert;
+    // on the far plane, behind everything
+    gl_Position = vec4(vert, 1.0, 1.0);
+}
@@ -0,0 +1,17 @@
+#version 330
+
+uniform sampler2D tex;
+
+in vec2 fragTexCoord;
+in vec3 fragColor;
+
+out vec4 color;
+
+void main() {
+    // glyphs are white in the atlas so the color tints them
+    vec4 glyph = texture(tex, fragTexCoord);
+    if (glyph.a < 0.1) {
+        discard;
+    }
+    color = vec4(fragColor * glyph.rgb, 1.0);
+}
@@ -0,0 +1,14 @@
+#version 330
+
+in vec3 vert;
+in vec2 texCoord;
+in vec3 color;
+
+out vec2 fragTexCoord;
+out vec3 fragColor;
+
+void main() {
+    fragTexCoord = texCoord;
+    fragColor = color;
+    gl_Position = vec4(vert, 1);
+}